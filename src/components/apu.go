@@ -0,0 +1,244 @@
+package components
+
+// Timer periods in CPU cycles, indexed by the 4-bit period field of $400E
+// and $4010. Dendy reuses the NTSC tables since its APU counts CPU cycles
+// like an NTSC 2A03.
+var noisePeriodsNTSC = [16]uint16{4, 8, 16, 32, 64, 96, 128, 160, 202, 254, 380, 508, 762, 1016, 2034, 4068}
+var noisePeriodsPAL = [16]uint16{4, 8, 14, 30, 60, 88, 118, 148, 188, 236, 354, 472, 708, 944, 1890, 3778}
+var dmcRatesNTSC = [16]uint16{428, 380, 340, 320, 286, 254, 226, 214, 190, 160, 142, 128, 106, 84, 72, 54}
+var dmcRatesPAL = [16]uint16{398, 354, 316, 298, 276, 236, 210, 198, 176, 148, 132, 118, 98, 78, 66, 50}
+
+// Frame counter steps in CPU cycles for the 4-step and 5-step sequences.
+var frameCounterStepsNTSC = [2][]uint32{{7457, 14913, 22371, 29829}, {7457, 14913, 22371, 29829, 37281}}
+var frameCounterStepsPAL = [2][]uint32{{8313, 16627, 24939, 33253}, {8313, 16627, 24939, 33253, 41565}}
+
+// APU is the 2A03 sound unit: two pulse channels, a triangle, a noise
+// channel and the DMC, driven by a frame counter that clocks envelopes and
+// linear counters every quarter frame and length counters and sweeps every
+// half frame. Its timers and the frame counter run on CPU cycles, so the
+// region tables cover the PAL differences; Dendy keeps the NTSC ones.
+type APU struct {
+	bus CPUBus
+
+	pulses   [2]pulseChannel
+	sweeps   [2]sweepUnit
+	triangle triangleChannel
+	noise    noiseChannel
+	dmc      dmcChannel
+
+	cycle           uint64
+	frameCycle      uint32
+	fiveStepMode    bool
+	frameIRQInhibit bool
+	frameIRQPending bool
+
+	// frameCounterReset counts down the CPU cycles between a $4017 write
+	// and the sequencer restarting.
+	frameCounterReset uint8
+
+	noisePeriods      *[16]uint16
+	dmcRates          *[16]uint16
+	frameCounterSteps *[2][]uint32
+}
+
+// ConnectBus gives the DMC memory reader access to CPU memory.
+func (this *APU) ConnectBus(bus CPUBus) {
+	this.bus = bus
+}
+
+func (this *APU) setRegion(region Region) {
+	if region == REGION_PAL {
+		this.noisePeriods = &noisePeriodsPAL
+		this.dmcRates = &dmcRatesPAL
+		this.frameCounterSteps = &frameCounterStepsPAL
+	} else {
+		this.noisePeriods = &noisePeriodsNTSC
+		this.dmcRates = &dmcRatesNTSC
+		this.frameCounterSteps = &frameCounterStepsNTSC
+	}
+}
+
+func (this *APU) CPUWrite(addr uint16, data uint8) {
+	switch {
+	case addr <= 0x4003:
+		this.pulses[0].write(addr, data)
+		if addr == 0x4001 {
+			this.sweeps[0].write(data)
+		}
+	case addr <= 0x4007:
+		this.pulses[1].write(addr, data)
+		if addr == 0x4005 {
+			this.sweeps[1].write(data)
+		}
+	case addr <= 0x400B:
+		this.triangle.write(addr, data)
+	case addr <= 0x400F:
+		this.noise.write(addr, data, this.noisePeriods)
+	case addr <= 0x4013:
+		this.dmc.write(addr, data, this.dmcRates)
+	case addr == 0x4015:
+		this.pulses[0].setEnabled(data&0x01 != 0)
+		this.pulses[1].setEnabled(data&0x02 != 0)
+		this.triangle.setEnabled(data&0x04 != 0)
+		this.noise.setEnabled(data&0x08 != 0)
+		this.dmc.setEnabled(data&0x10 != 0)
+	case addr == 0x4017:
+		this.fiveStepMode = data&0x80 != 0
+		this.frameIRQInhibit = data&0x40 != 0
+		if this.frameIRQInhibit {
+			this.frameIRQPending = false
+		}
+
+		// The sequencer restarts 3 CPU cycles after a write on an APU
+		// cycle, 4 after one in between.
+		this.frameCounterReset = 3
+		if this.cycle%2 != 0 {
+			this.frameCounterReset = 4
+		}
+	}
+}
+
+// CPURead handles $4015, the only readable APU register: which length
+// counters are running, whether the DMC has bytes left and both IRQ flags.
+// Reading it acknowledges the frame IRQ. Bit 5 is not driven.
+func (this *APU) CPURead(addr uint16, readOnly bool) uint8 {
+	var data uint8 = 0x00
+
+	switch addr {
+	case 0x4015:
+		if this.pulses[0].lengthCounter > 0 {
+			data |= 0x01
+		}
+		if this.pulses[1].lengthCounter > 0 {
+			data |= 0x02
+		}
+		if this.triangle.lengthCounter > 0 {
+			data |= 0x04
+		}
+		if this.noise.lengthCounter > 0 {
+			data |= 0x08
+		}
+		if this.dmc.bytesRemaining > 0 {
+			data |= 0x10
+		}
+		if this.frameIRQPending {
+			data |= 0x40
+		}
+		if this.dmc.IRQPending {
+			data |= 0x80
+		}
+
+		if !readOnly {
+			this.frameIRQPending = false
+		}
+	}
+
+	return data
+}
+
+// IRQState reports whether the frame counter or the DMC holds the CPU IRQ
+// line low.
+func (this *APU) IRQState() bool {
+	return this.frameIRQPending || this.dmc.IRQPending
+}
+
+// clock runs once per CPU cycle.
+func (this *APU) clock() {
+	this.cycle++
+
+	if this.cycle%2 == 0 {
+		this.pulses[0].clockTimer()
+		this.pulses[1].clockTimer()
+	}
+	this.triangle.clockTimer()
+	this.noise.clockTimer()
+	this.dmc.clock(this.readSample)
+
+	this.clockFrameCounter()
+}
+
+func (this *APU) readSample(addr uint16) uint8 {
+	return this.bus.CPURead(addr, false)
+}
+
+// clockFrameCounter steps the sequencer. The 4-step sequence raises the
+// frame IRQ on its last step; the 5-step one does nothing on its fourth
+// and never raises it.
+func (this *APU) clockFrameCounter() {
+	if this.frameCounterReset > 0 {
+		this.frameCounterReset--
+		if this.frameCounterReset == 0 {
+			this.frameCycle = 0
+			if this.fiveStepMode {
+				this.clockQuarterFrame()
+				this.clockHalfFrame()
+			}
+		}
+	}
+
+	this.frameCycle++
+
+	steps := this.frameCounterSteps[0]
+	if this.fiveStepMode {
+		steps = this.frameCounterSteps[1]
+	}
+
+	last := steps[len(steps)-1]
+
+	switch this.frameCycle {
+	case steps[0], steps[2]:
+		this.clockQuarterFrame()
+	case steps[1], last:
+		this.clockQuarterFrame()
+		this.clockHalfFrame()
+	}
+
+	if this.frameCycle == last && !this.fiveStepMode && !this.frameIRQInhibit {
+		this.frameIRQPending = true
+	}
+
+	if this.frameCycle > last {
+		this.frameCycle = 0
+	}
+}
+
+func (this *APU) clockQuarterFrame() {
+	this.pulses[0].clockEnvelope()
+	this.pulses[1].clockEnvelope()
+	this.triangle.clockLinearCounter()
+	this.noise.envelope.clock()
+}
+
+func (this *APU) clockHalfFrame() {
+	for i := range this.pulses {
+		this.pulses[i].clockLengthCounter()
+		this.sweeps[i].clock(&this.pulses[i])
+	}
+	this.triangle.clockLengthCounter()
+	this.noise.clockLengthCounter()
+}
+
+// sample mixes the five channels on the 0-1 scale of the 2A03 mixer.
+func (this *APU) sample() float32 {
+	var pulses [2]uint8
+	for i := range this.pulses {
+		if !this.sweeps[i].mutes(&this.pulses[i]) {
+			pulses[i] = this.pulses[i].output()
+		}
+	}
+
+	return mixPulses(pulses[0], pulses[1]) + mixTND(this.triangle.output(), this.noise.output(), this.dmc.output())
+}
+
+// reset silences every channel, as writing 0 to $4015 does, and restarts
+// the frame counter in the mode it was in.
+func (this *APU) reset() {
+	this.pulses = [2]pulseChannel{}
+	this.sweeps = [2]sweepUnit{{onesComplement: true}, {}}
+	this.triangle = triangleChannel{}
+	this.noise = noiseChannel{shiftReg: 1}
+	this.dmc = dmcChannel{bitsRemaining: 8, silence: true}
+	this.frameIRQPending = false
+	this.frameCycle = 0
+	this.frameCounterReset = 0
+}
@@ -0,0 +1,125 @@
+package components
+
+// dmcChannel is the delta modulation channel, $4010-$4013. It plays 1-bit
+// delta samples that its memory reader fetches from $C000-$FFFF, raising
+// the CPU's 7-bit output level by 2 for each 1 bit and lowering it for
+// each 0. The reader borrows the CPU bus for each byte; the 1-4 cycle stall
+// that costs the CPU is not emulated.
+type dmcChannel struct {
+	IRQEnabled bool
+	IRQPending bool
+	loop       bool
+
+	timerPeriod  uint16
+	timerCounter uint16
+
+	sampleAddress uint16
+	sampleLength  uint16
+
+	currentAddress uint16
+	bytesRemaining uint16
+	sampleBuffer   uint8
+	bufferFull     bool
+
+	shiftReg      uint8
+	bitsRemaining uint8
+	silence       bool
+	level         uint8
+}
+
+func (this *dmcChannel) write(register uint16, data uint8, rates *[16]uint16) {
+	switch register & 0x03 {
+	case 0:
+		this.IRQEnabled = data&0x80 != 0
+		if !this.IRQEnabled {
+			this.IRQPending = false
+		}
+		this.loop = data&0x40 != 0
+		this.timerPeriod = rates[data&0x0F]
+	case 1:
+		this.level = data & 0x7F
+	case 2:
+		this.sampleAddress = 0xC000 | uint16(data)<<6
+	case 3:
+		this.sampleLength = uint16(data)<<4 | 0x0001
+	}
+}
+
+// setEnabled is bit 4 of $4015: clearing it stops the sample after the
+// byte being played, setting it restarts a sample that has finished.
+func (this *dmcChannel) setEnabled(enabled bool) {
+	this.IRQPending = false
+
+	if !enabled {
+		this.bytesRemaining = 0
+	} else if this.bytesRemaining == 0 {
+		this.restart()
+	}
+}
+
+func (this *dmcChannel) restart() {
+	this.currentAddress = this.sampleAddress
+	this.bytesRemaining = this.sampleLength
+}
+
+// clock runs on every CPU cycle, with read fetching the next sample byte
+// from CPU memory when the buffer runs empty.
+func (this *dmcChannel) clock(read func(addr uint16) uint8) {
+	if !this.bufferFull && this.bytesRemaining > 0 {
+		this.fetch(read)
+	}
+
+	if this.timerCounter > 1 {
+		this.timerCounter--
+		return
+	}
+	this.timerCounter = this.timerPeriod
+
+	if !this.silence {
+		if this.shiftReg&0x01 != 0 && this.level <= 125 {
+			this.level += 2
+		} else if this.shiftReg&0x01 == 0 && this.level >= 2 {
+			this.level -= 2
+		}
+	}
+	this.shiftReg >>= 1
+
+	if this.bitsRemaining > 0 {
+		this.bitsRemaining--
+	}
+	if this.bitsRemaining > 0 {
+		return
+	}
+
+	this.bitsRemaining = 8
+	this.silence = !this.bufferFull
+	if this.bufferFull {
+		this.shiftReg = this.sampleBuffer
+		this.bufferFull = false
+	}
+}
+
+func (this *dmcChannel) fetch(read func(addr uint16) uint8) {
+	this.sampleBuffer = read(this.currentAddress)
+	this.bufferFull = true
+
+	this.currentAddress++
+	if this.currentAddress == 0x0000 {
+		this.currentAddress = 0x8000
+	}
+
+	this.bytesRemaining--
+	if this.bytesRemaining > 0 {
+		return
+	}
+
+	if this.loop {
+		this.restart()
+	} else if this.IRQEnabled {
+		this.IRQPending = true
+	}
+}
+
+func (this *dmcChannel) output() uint8 {
+	return this.level
+}
@@ -0,0 +1,48 @@
+package components
+
+// envelope is the volume unit shared by the pulse and noise channels: a
+// constant volume, or a level decaying from 15 once per quarter frame,
+// optionally looping back to 15.
+type envelope struct {
+	constantVolume bool
+	volume         uint8 // the constant volume, or the decay divider period
+	loop           bool
+	start          bool
+	divider        uint8
+	decay          uint8
+}
+
+// write takes the low six bits of $4000, $4004 or $400C.
+func (this *envelope) write(data uint8) {
+	this.loop = data&0x20 != 0
+	this.constantVolume = data&0x10 != 0
+	this.volume = data & 0x0F
+}
+
+func (this *envelope) clock() {
+	if this.start {
+		this.start = false
+		this.decay = 15
+		this.divider = this.volume
+		return
+	}
+
+	if this.divider > 0 {
+		this.divider--
+		return
+	}
+
+	this.divider = this.volume
+	if this.decay > 0 {
+		this.decay--
+	} else if this.loop {
+		this.decay = 15
+	}
+}
+
+func (this *envelope) level() uint8 {
+	if this.constantVolume {
+		return this.volume
+	}
+	return this.decay
+}
@@ -0,0 +1,74 @@
+package components
+
+// noiseChannel is $400C-$400F: a 15-bit linear feedback shift register
+// clocked at one of sixteen periods from the region's table.
+type noiseChannel struct {
+	enabled bool
+
+	// shortMode feeds back bit 6 instead of bit 1, for a 93-step metallic
+	// loop instead of a 32767-step hiss.
+	shortMode    bool
+	shiftReg     uint16
+	timerPeriod  uint16
+	timerCounter uint16
+
+	lengthCounter uint8
+	lengthHalted  bool
+
+	envelope
+}
+
+func (this *noiseChannel) write(register uint16, data uint8, periods *[16]uint16) {
+	switch register & 0x03 {
+	case 0:
+		this.lengthHalted = data&0x20 != 0
+		this.envelope.write(data)
+	case 1:
+		break
+	case 2:
+		this.shortMode = data&0x80 != 0
+		this.timerPeriod = periods[data&0x0F]
+	case 3:
+		if this.enabled {
+			this.lengthCounter = lengthCounterTable[data>>3]
+		}
+		this.envelope.start = true
+	}
+}
+
+func (this *noiseChannel) setEnabled(enabled bool) {
+	this.enabled = enabled
+	if !enabled {
+		this.lengthCounter = 0
+	}
+}
+
+// clockTimer runs on every CPU cycle; the period table is in CPU cycles.
+func (this *noiseChannel) clockTimer() {
+	if this.timerCounter > 1 {
+		this.timerCounter--
+		return
+	}
+
+	this.timerCounter = this.timerPeriod
+
+	tap := uint16(1)
+	if this.shortMode {
+		tap = 6
+	}
+	feedback := (this.shiftReg ^ this.shiftReg>>tap) & 0x0001
+	this.shiftReg = this.shiftReg>>1 | feedback<<14
+}
+
+func (this *noiseChannel) clockLengthCounter() {
+	if this.lengthCounter > 0 && !this.lengthHalted {
+		this.lengthCounter--
+	}
+}
+
+func (this *noiseChannel) output() uint8 {
+	if this.lengthCounter == 0 || this.shiftReg&0x0001 != 0 {
+		return 0
+	}
+	return this.envelope.level()
+}
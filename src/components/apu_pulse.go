@@ -0,0 +1,167 @@
+package components
+
+// Length counter loads, indexed by bits 3-7 of the fourth channel register.
+var lengthCounterTable = [32]uint8{
+	10, 254, 20, 2, 40, 4, 80, 6, 160, 8, 60, 10, 14, 12, 26, 14,
+	12, 16, 24, 18, 48, 20, 96, 22, 192, 24, 72, 26, 16, 28, 32, 30,
+}
+
+// Duty cycle waveforms, read from step 0 to 7 as the sequencer counts down.
+var pulseDutySequences = [4][8]uint8{
+	{0, 1, 0, 0, 0, 0, 0, 0},
+	{0, 1, 1, 0, 0, 0, 0, 0},
+	{0, 1, 1, 1, 1, 0, 0, 0},
+	{1, 0, 0, 1, 1, 1, 1, 1},
+}
+
+// pulseChannel is a square wave channel with its envelope and length
+// counter, laid out like $4000-$4003. It has no sweep unit, which is all
+// the MMC5 pulses need; the 2A03 pulses add one on top.
+type pulseChannel struct {
+	enabled bool
+
+	duty         uint8
+	dutyStep     uint8
+	timerPeriod  uint16
+	timerCounter uint16
+
+	lengthCounter uint8
+	lengthHalted  bool
+
+	envelope
+}
+
+func (this *pulseChannel) write(register uint16, data uint8) {
+	switch register & 0x03 {
+	case 0:
+		this.duty = data >> 6
+		this.lengthHalted = data&0x20 != 0
+		this.envelope.write(data)
+	case 1:
+		break
+	case 2:
+		this.timerPeriod = this.timerPeriod&0x0700 | uint16(data)
+	case 3:
+		this.timerPeriod = this.timerPeriod&0x00FF | uint16(data&0x07)<<8
+		if this.enabled {
+			this.lengthCounter = lengthCounterTable[data>>3]
+		}
+		this.dutyStep = 0
+		this.envelope.start = true
+	}
+}
+
+func (this *pulseChannel) setEnabled(enabled bool) {
+	this.enabled = enabled
+	if !enabled {
+		this.lengthCounter = 0
+	}
+}
+
+// clockTimer runs once per APU cycle, every other CPU cycle.
+func (this *pulseChannel) clockTimer() {
+	if this.timerCounter == 0 {
+		this.timerCounter = this.timerPeriod
+		this.dutyStep = (this.dutyStep + 1) & 0x07
+	} else {
+		this.timerCounter--
+	}
+}
+
+func (this *pulseChannel) clockEnvelope() {
+	this.envelope.clock()
+}
+
+func (this *pulseChannel) clockLengthCounter() {
+	if this.lengthCounter > 0 && !this.lengthHalted {
+		this.lengthCounter--
+	}
+}
+
+// output is the current 4-bit level. Periods below 8 would be ultrasonic
+// and are silenced.
+func (this *pulseChannel) output() uint8 {
+	if this.lengthCounter == 0 || this.timerPeriod < 8 || pulseDutySequences[this.duty][this.dutyStep] == 0 {
+		return 0
+	}
+
+	return this.envelope.level()
+}
+
+// sweepUnit bends the period of a 2A03 pulse channel every half frame,
+// configured by $4001 or $4005. The first pulse negates with one's
+// complement, so it sweeps down one step further than the second.
+type sweepUnit struct {
+	enabled        bool
+	negate         bool
+	onesComplement bool
+	reload         bool
+	period         uint8
+	shift          uint8
+	divider        uint8
+}
+
+func (this *sweepUnit) write(data uint8) {
+	this.enabled = data&0x80 != 0
+	this.period = (data >> 4) & 0x07
+	this.negate = data&0x08 != 0
+	this.shift = data & 0x07
+	this.reload = true
+}
+
+func (this *sweepUnit) targetPeriod(pulse *pulseChannel) uint16 {
+	change := pulse.timerPeriod >> this.shift
+	if !this.negate {
+		return pulse.timerPeriod + change
+	}
+
+	if this.onesComplement {
+		change++
+	}
+	if change > pulse.timerPeriod {
+		return 0
+	}
+	return pulse.timerPeriod - change
+}
+
+// mutes reports whether the channel is silenced by a period out of range,
+// which happens even while the sweep itself is disabled.
+func (this *sweepUnit) mutes(pulse *pulseChannel) bool {
+	return pulse.timerPeriod < 8 || this.targetPeriod(pulse) > 0x07FF
+}
+
+func (this *sweepUnit) clock(pulse *pulseChannel) {
+	if this.divider == 0 && this.enabled && this.shift > 0 && !this.mutes(pulse) {
+		pulse.timerPeriod = this.targetPeriod(pulse)
+	}
+
+	if this.divider == 0 || this.reload {
+		this.divider = this.period
+		this.reload = false
+	} else {
+		this.divider--
+	}
+}
+
+// mixPulses and mixPCM are the nonlinear 2A03 mixer approximations, so
+// expansion audio comes out on the same scale as the console's channels.
+func mixPulses(pulse1 uint8, pulse2 uint8) float32 {
+	if pulse1+pulse2 == 0 {
+		return 0
+	}
+	return 95.88 / (8128.0/float32(pulse1+pulse2) + 100)
+}
+
+func mixPCM(pcm uint8) float32 {
+	if pcm == 0 {
+		return 0
+	}
+	return 159.79 / (1/(float32(pcm)/22638.0) + 100)
+}
+
+func mixTND(triangle uint8, noise uint8, dmc uint8) float32 {
+	if triangle+noise+dmc == 0 {
+		return 0
+	}
+	return 159.79 / (1/(float32(triangle)/8227.0+float32(noise)/12241.0+float32(dmc)/22638.0) + 100)
+}
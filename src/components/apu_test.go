@@ -0,0 +1,164 @@
+package components
+
+import "testing"
+
+func newTestAPU(region Region, memory CPUBus) *APU {
+	apu := &APU{}
+	apu.ConnectBus(memory)
+	apu.reset()
+	apu.setRegion(region)
+	return apu
+}
+
+func TestFrameIRQFollowsRegion(t *testing.T) {
+	tests := map[Region]int{REGION_NTSC: 29829, REGION_PAL: 33253, REGION_DENDY: 29829}
+
+	for region, expected := range tests {
+		apu := newTestAPU(region, NewFlatMemory())
+
+		cycles := 0
+		for !apu.IRQState() && cycles < 100000 {
+			apu.clock()
+			cycles++
+		}
+
+		if cycles != expected {
+			t.Errorf("%s: frame IRQ after %d cycles, want %d", region, cycles, expected)
+		}
+	}
+}
+
+func TestFiveStepSequenceRaisesNoIRQ(t *testing.T) {
+	apu := newTestAPU(REGION_NTSC, NewFlatMemory())
+	apu.CPUWrite(0x4017, 0x80)
+
+	for i := 0; i < 100000; i++ {
+		apu.clock()
+		if apu.IRQState() {
+			t.Fatalf("frame IRQ after %d cycles in 5-step mode", i+1)
+		}
+	}
+}
+
+func TestLengthCounterFollowsRegionHalfFrames(t *testing.T) {
+	// A length of 2 lasts two half frames, up to the last step of the
+	// 4-step sequence.
+	tests := map[Region]int{REGION_NTSC: 29829, REGION_PAL: 33253}
+
+	for region, expected := range tests {
+		apu := newTestAPU(region, NewFlatMemory())
+		apu.CPUWrite(0x4017, 0x40)
+		apu.CPUWrite(0x4015, 0x08)
+		apu.CPUWrite(0x400F, 0x18)
+
+		cycles := 0
+		for apu.noise.lengthCounter > 0 && cycles < 100000 {
+			apu.clock()
+			cycles++
+		}
+
+		// The $4017 write restarts the sequencer, which counts its first
+		// cycle on the third cycle after the write.
+		if cycles != expected+2 {
+			t.Errorf("%s: length counter ran out after %d cycles, want %d", region, cycles, expected+2)
+		}
+	}
+}
+
+func TestNoisePeriodFollowsRegion(t *testing.T) {
+	tests := map[Region]int{REGION_NTSC: 4068, REGION_PAL: 3778, REGION_DENDY: 4068}
+
+	for region, expected := range tests {
+		apu := newTestAPU(region, NewFlatMemory())
+		apu.CPUWrite(0x400E, 0x0F)
+
+		shifts := 0
+		lastShiftReg := apu.noise.shiftReg
+		for cycles := 1; shifts < 3; cycles++ {
+			apu.clock()
+			if apu.noise.shiftReg == lastShiftReg {
+				continue
+			}
+			lastShiftReg = apu.noise.shiftReg
+			shifts++
+
+			// The first shift comes as soon as the timer reloads.
+			if shifts == 3 && cycles != 1+2*expected {
+				t.Errorf("%s: third LFSR shift on cycle %d, want %d", region, cycles, 1+2*expected)
+			}
+		}
+	}
+}
+
+func TestDMCRateFollowsRegion(t *testing.T) {
+	tests := map[Region]int{REGION_NTSC: 54, REGION_PAL: 50, REGION_DENDY: 54}
+
+	for region, expected := range tests {
+		memory := NewFlatMemory()
+		memory.Load(0xC000, []uint8{0xFF})
+
+		apu := newTestAPU(region, memory)
+		apu.CPUWrite(0x4010, 0x0F)
+		apu.CPUWrite(0x4011, 0x40)
+		apu.CPUWrite(0x4012, 0x00)
+		apu.CPUWrite(0x4013, 0x00)
+		apu.CPUWrite(0x4015, 0x10)
+
+		var changes []int
+		level := apu.dmc.output()
+		for cycles := 1; len(changes) < 2 && cycles < 10000; cycles++ {
+			apu.clock()
+			if apu.dmc.output() != level {
+				level = apu.dmc.output()
+				changes = append(changes, cycles)
+			}
+		}
+
+		if len(changes) < 2 || changes[1]-changes[0] != expected || level != 0x44 {
+			t.Errorf("%s: DMC output changed on cycles %v to $%02X, want %d cycles apart", region, changes, level, expected)
+		}
+	}
+}
+
+func TestStatusRead(t *testing.T) {
+	bus := NewBus()
+	bus.CPUWrite(0x4015, 0x0F)
+	bus.CPUWrite(0x4003, 0x08) // pulse 1 length 254
+	bus.CPUWrite(0x400B, 0x08) // triangle
+	bus.CPUWrite(0x4012, 0x00)
+	bus.CPUWrite(0x4013, 0x01) // 17-byte sample
+	bus.CPUWrite(0x4015, 0x15)
+
+	if status := bus.CPURead(0x4015, false); status&0xDF != 0x15 {
+		t.Errorf("status $%02X after enabling pulse 1, triangle and DMC, want $15", status)
+	}
+
+	for !bus.apu.frameIRQPending {
+		bus.apu.clock()
+	}
+
+	if status := bus.CPURead(0x4015, true); status&0x40 == 0 {
+		t.Errorf("status $%02X does not report the frame IRQ", status)
+	}
+	if status := bus.CPURead(0x4015, false); status&0x40 == 0 {
+		t.Errorf("status $%02X does not report the frame IRQ", status)
+	}
+	if status := bus.CPURead(0x4015, false); status&0x40 != 0 {
+		t.Errorf("status $%02X still reports the frame IRQ after a read", status)
+	}
+
+	bus.CPUWrite(0x4010, 0x8F) // DMC IRQ on, fastest rate
+	bus.CPUWrite(0x4015, 0x10)
+	for i := 0; i < 17*8*54+1000; i++ {
+		bus.apu.clock()
+	}
+
+	if status := bus.CPURead(0x4015, false); status&0x90 != 0x80 {
+		t.Errorf("status $%02X after the DMC sample ended, want the DMC IRQ and no bytes left", status)
+	}
+
+	bus.CPUWrite(0x4015, 0x00)
+	if status := bus.CPURead(0x4015, false); status&0xDF != 0x00 {
+		t.Errorf("status $%02X after disabling every channel", status)
+	}
+}
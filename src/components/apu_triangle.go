@@ -0,0 +1,92 @@
+package components
+
+// Triangle steps, read from step 0 to 31.
+var triangleSequence = [32]uint8{
+	15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1, 0,
+	0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+}
+
+// triangleChannel is $4008-$400B. Besides the length counter it has a
+// linear counter, clocked every quarter frame, and its timer runs on every
+// CPU cycle rather than every APU cycle.
+type triangleChannel struct {
+	enabled bool
+
+	step         uint8
+	timerPeriod  uint16
+	timerCounter uint16
+
+	lengthCounter uint8
+
+	// control halts the length counter and keeps the linear counter
+	// reloading.
+	control             bool
+	linearReloadValue   uint8
+	linearCounter       uint8
+	linearCounterReload bool
+}
+
+func (this *triangleChannel) write(register uint16, data uint8) {
+	switch register & 0x03 {
+	case 0:
+		this.control = data&0x80 != 0
+		this.linearReloadValue = data & 0x7F
+	case 1:
+		break
+	case 2:
+		this.timerPeriod = this.timerPeriod&0x0700 | uint16(data)
+	case 3:
+		this.timerPeriod = this.timerPeriod&0x00FF | uint16(data&0x07)<<8
+		if this.enabled {
+			this.lengthCounter = lengthCounterTable[data>>3]
+		}
+		this.linearCounterReload = true
+	}
+}
+
+func (this *triangleChannel) setEnabled(enabled bool) {
+	this.enabled = enabled
+	if !enabled {
+		this.lengthCounter = 0
+	}
+}
+
+func (this *triangleChannel) clockTimer() {
+	if this.timerCounter > 0 {
+		this.timerCounter--
+		return
+	}
+
+	this.timerCounter = this.timerPeriod
+	if this.lengthCounter > 0 && this.linearCounter > 0 {
+		this.step = (this.step + 1) & 0x1F
+	}
+}
+
+func (this *triangleChannel) clockLinearCounter() {
+	if this.linearCounterReload {
+		this.linearCounter = this.linearReloadValue
+	} else if this.linearCounter > 0 {
+		this.linearCounter--
+	}
+
+	if !this.control {
+		this.linearCounterReload = false
+	}
+}
+
+func (this *triangleChannel) clockLengthCounter() {
+	if this.lengthCounter > 0 && !this.control {
+		this.lengthCounter--
+	}
+}
+
+// output keeps the last step when the channel is silenced, like the
+// hardware. Periods below 2 would be ultrasonic and are heard as the
+// middle of the waveform.
+func (this *triangleChannel) output() uint8 {
+	if this.timerPeriod < 2 {
+		return 7
+	}
+	return triangleSequence[this.step]
+}
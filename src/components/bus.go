@@ -2,60 +2,267 @@ package components
 
 const RAM_SIZE_KB = 2 * 1024
 
+var _ CPUBus = (*Bus)(nil)
+
+// oamDMA copies a CPU page into PPU OAM through $2004 while the CPU is
+// halted: one halt cycle, one more to align when needed, then a read on
+// every even cycle and a write on every odd one, 513 or 514 cycles in all.
+type oamDMA struct {
+	pending bool
+	active  bool
+	halting bool
+	page    uint8
+	index   uint16
+	data    uint8
+	hasData bool
+}
+
 type Bus struct {
-	cpu                CPU6502
+	cpu                *CPU6502
 	ppu                PPU
+	apu                APU
+	controllers        [2]Controller
 	cartridge          *Cartridge
 	cpuRAM             [RAM_SIZE_KB]uint8
-	systemClockCounter uint32
+	systemClockCounter uint64
+
+	oamDMA oamDMA
+
+	region       Region
+	timing       regionTiming
+	cpuClockTick uint8
+
+	// openBus holds the last value driven on the CPU data bus. Reads that
+	// nothing answers see it again, as do the bits a register leaves floating.
+	openBus uint8
 }
 
 func NewBus() *Bus {
 	newBus := &Bus{
-		cpu:    CPU6502{},
+		cpu:    NewCPU6502(),
 		cpuRAM: [RAM_SIZE_KB]uint8{},
 	}
 
 	newBus.cpu.ConnectBus(newBus)
+	newBus.apu.ConnectBus(newBus)
+	newBus.apu.reset()
+	newBus.SetRegion(REGION_NTSC)
 
 	return newBus
 }
 
+// CPU memory map:
+//
+//	$0000-$1FFF  2KB internal RAM, mirrored every $0800
+//	$2000-$3FFF  PPU registers, mirrored every 8 bytes
+//	$4000-$4017  APU and I/O registers
+//	$4018-$401F  APU and I/O test registers, disabled on retail consoles
+//	$4020-$FFFF  cartridge space
 func (this *Bus) CPUWrite(addr uint16, data uint8) {
+	this.openBus = data
+
 	isWithinCPUAddressRange := addr >= 0x0000 && addr <= 0x1FFF
 	isWithinPPUAddressRange := addr >= 0x2000 && addr <= 0x3FFF
+	isOAMDMA := addr == 0x4014
+	isControllerStrobe := addr == 0x4016
+	isWithinAPUAddressRange := addr >= 0x4000 && addr <= 0x4017
+	isWithinCartridgeAddressRange := addr >= 0x4020
 
 	if isWithinCPUAddressRange {
 		this.cpuRAM[addr&0x7FF] = data
 	} else if isWithinPPUAddressRange {
 		this.ppu.CPUWrite(addr&0x0007, &data)
+
+		// The cartridge connector carries the whole CPU bus, so boards
+		// like MMC5 can follow what is written to the PPU.
+		if this.cartridge != nil {
+			this.cartridge.CPUWrite(addr, &data)
+		}
+	} else if isOAMDMA {
+		this.oamDMA.pending = true
+		this.oamDMA.page = data
+	} else if isControllerStrobe {
+		this.controllers[0].Write(data)
+		this.controllers[1].Write(data)
+	} else if isWithinAPUAddressRange {
+		this.apu.CPUWrite(addr, data)
+	} else if isWithinCartridgeAddressRange && this.cartridge != nil {
+		this.cartridge.CPUWrite(addr, &data)
 	}
 }
 
 func (this *Bus) CPURead(addr uint16, readOnly bool) uint8 {
-	var data uint8 = 0x00
+	data := this.openBus
 	isWithinCPUAddressRange := addr >= 0x0000 && addr <= 0x1FFF
 	isWithinPPUAddressRange := addr >= 0x2000 && addr <= 0x3FFF
+	isAPUStatus := addr == 0x4015
+	isControllerPort := addr == 0x4016 || addr == 0x4017
+	isWithinCartridgeAddressRange := addr >= 0x4020
 
 	if isWithinCPUAddressRange {
 		data = this.cpuRAM[addr&0x7FF]
 	} else if isWithinPPUAddressRange {
 		data = this.ppu.CPURead(addr&0x0007, readOnly)
+	} else if isAPUStatus {
+		// $4015 is read inside the 2A03, so the external bus keeps its value
+		// and bit 5 floats.
+		return this.apu.CPURead(addr, readOnly)&0xDF | this.openBus&0x20
+	} else if isControllerPort {
+		// Only D0-D4 are driven by the ports.
+		data = this.controllers[addr&0x0001].Read(readOnly)&0x1F | this.openBus&0xE0
+	} else if isWithinCartridgeAddressRange && this.cartridge != nil {
+		if cartridgeData, driven := this.cartridge.CPURead(addr, readOnly); driven {
+			drivenBits := this.cartridge.DrivenBits(addr)
+			data = cartridgeData&drivenBits | this.openBus&^drivenBits
+		}
+	}
+
+	if !readOnly {
+		this.openBus = data
 	}
 
 	return data
 }
 
+// AudioSample is the current mixed audio level: the 2A03 channels plus the
+// cartridge's expansion audio.
+func (this *Bus) AudioSample() float32 {
+	sample := this.apu.sample()
+
+	if this.cartridge != nil {
+		sample += this.cartridge.AudioSample()
+	}
+
+	return sample
+}
+
+// SetControllerButtons updates the buttons held on controller port 0 or 1,
+// using the BUTTON_* bits.
+func (this *Bus) SetControllerButtons(port int, buttons uint8) {
+	this.controllers[port].SetButtons(buttons)
+}
+
+// InsertCartridge also switches the console to the region the cartridge
+// header asks for; call SetRegion afterwards to override it.
 func (this *Bus) InsertCartridge(cartridge *Cartridge) {
 	this.cartridge = cartridge
 	this.ppu.ConnectCartridge(cartridge)
+	this.SetRegion(cartridge.region)
+}
+
+func (this *Bus) SetRegion(region Region) {
+	this.region = region
+	this.timing = regionTimings[region]
+	this.ppu.setRegion(region)
+	this.apu.setRegion(region)
+	this.resetClockDividers()
+}
+
+func (this *Bus) Region() Region {
+	return this.region
+}
+
+// resetClockDividers lines the dividers up so the CPU is clocked on the
+// first tick, then every cpuClockDivider master cycles.
+func (this *Bus) resetClockDividers() {
+	this.cpuClockTick = this.timing.cpuClockDivider - this.timing.ppuClockDivider
 }
 
 func (this *Bus) Reset() {
+	if this.cartridge != nil {
+		this.cartridge.Reset()
+	}
 	this.cpu.ResetSignal()
+	this.ppu.reset()
+	this.apu.reset()
+	this.oamDMA = oamDMA{}
 	this.systemClockCounter = 0
+	this.resetClockDividers()
 }
 
+// Clock advances the console by one PPU dot. The CPU is clocked whenever
+// the master cycles elapsed since its last cycle reach its divider: every
+// third dot on NTSC and Dendy, every 3.2 dots on PAL. The PPU's NMI output
+// is forwarded to the CPU on the tick that raised it.
 func (this *Bus) Clock() {
+	this.ppu.clock()
+
+	this.cpuClockTick += this.timing.ppuClockDivider
+	if this.cpuClockTick >= this.timing.cpuClockDivider {
+		this.cpuClockTick -= this.timing.cpuClockDivider
+		this.clockCPU()
+	}
+
+	if this.ppu.nmi {
+		this.ppu.nmi = false
+		this.cpu.NonMaskableInterruptRequestSignal()
+	}
+
+	this.systemClockCounter++
+}
+
+// clockCPU clocks the APU and the cartridge, forwards their IRQ lines, then
+// gives the CPU cycle to a pending DMA once the instruction that wrote $4014
+// has finished.
+func (this *Bus) clockCPU() {
+	if this.oamDMA.pending && this.cpu.InstructionComplete() {
+		this.oamDMA = oamDMA{active: true, halting: true, page: this.oamDMA.page}
+	}
+
+	this.apu.clock()
+	if this.apu.IRQState() {
+		this.cpu.InterruptRequestSignal()
+	}
+
+	// The cartridge IRQ is a level: it is held on the CPU for every cycle
+	// the board keeps it asserted.
+	if this.cartridge != nil {
+		this.cartridge.Clock()
+
+		if this.cartridge.IRQState() {
+			this.cpu.InterruptRequestSignal()
+		}
+	}
+
+	if !this.oamDMA.active {
+		this.cpu.ClockSignal()
+		return
+	}
+
+	isReadCycle := this.cpu.clockCount%2 == 0
+
+	if this.oamDMA.halting {
+		this.oamDMA.halting = false
+	} else if isReadCycle {
+		this.oamDMA.data = this.CPURead(uint16(this.oamDMA.page)<<8|this.oamDMA.index, false)
+		this.oamDMA.hasData = true
+	} else if this.oamDMA.hasData {
+		this.ppu.CPUWrite(0x0004, &this.oamDMA.data)
+		this.oamDMA.hasData = false
+		this.oamDMA.index++
+		this.oamDMA.active = this.oamDMA.index < PPU_OAM_SIZE
+	}
+
+	this.cpu.HaltSignal()
+}
+
+// RunFrame clocks the console until the PPU wraps back to scanline 0.
+func (this *Bus) RunFrame() {
+	this.ppu.frameComplete = false
+	for !this.ppu.frameComplete {
+		this.Clock()
+	}
+	this.ppu.frameComplete = false
+}
+
+// RunCycles clocks the console for the given number of CPU cycles.
+func (this *Bus) RunCycles(cpuCycles uint64) {
+	for target := this.cpu.clockCount + cpuCycles; this.cpu.clockCount < target; {
+		this.Clock()
+	}
+}
 
+// Frame is the picture the PPU rendered during the last frame.
+func (this *Bus) Frame() *[PPU_SCREEN_HEIGHT][PPU_SCREEN_WIDTH]uint8 {
+	return this.ppu.Frame()
 }
@@ -0,0 +1,284 @@
+package components
+
+import "testing"
+
+func TestUnmappedReadsReturnOpenBus(t *testing.T) {
+	bus := NewBus()
+
+	for _, addr := range []uint16{0x4018, 0x401F, 0x5000, 0x8000, 0xFFFF} {
+		bus.CPUWrite(0x0000, uint8(addr))
+		bus.CPURead(0x0000, false)
+
+		if data := bus.CPURead(addr, false); data != uint8(addr) {
+			t.Errorf("$%04X reads $%02X, want the last bus value $%02X", addr, data, uint8(addr))
+		}
+	}
+}
+
+func TestControllerPortsLeaveUpperBitsOpen(t *testing.T) {
+	bus := NewBus()
+	bus.SetControllerButtons(0, BUTTON_A)
+	bus.CPUWrite(0x4016, 1)
+	bus.CPUWrite(0x4016, 0)
+
+	bus.CPUWrite(0x0000, 0xFF)
+	bus.CPURead(0x0000, false)
+
+	if data := bus.CPURead(0x4016, false); data != 0xE1 {
+		t.Errorf("$4016 reads $%02X, want $E1: A pressed under bits 5-7 of $FF", data)
+	}
+
+	// The port read put $E1 on the bus; only bits 5-7 of it come back.
+	if data := bus.CPURead(0x4017, false); data != 0xE0 {
+		t.Errorf("$4017 reads $%02X, want $E0", data)
+	}
+}
+
+// $4015 is answered inside the 2A03: bit 5 shows the external bus, and the
+// read does not change it.
+func TestAPUStatusBit5IsOpenBus(t *testing.T) {
+	bus := NewBus()
+
+	for _, last := range []uint8{0x20, 0xDF} {
+		bus.CPUWrite(0x0000, last)
+		bus.CPURead(0x0000, false)
+
+		if data := bus.CPURead(0x4015, false); data&0x20 != last&0x20 {
+			t.Errorf("after $%02X on the bus, $4015 reads $%02X", last, data)
+		}
+		if bus.openBus != last {
+			t.Errorf("reading $4015 changed the bus from $%02X to $%02X", last, bus.openBus)
+		}
+	}
+}
+
+// The PPU drives only the top three bits of $2002; the rest is what was last
+// written to any PPU register.
+func TestPPUStatusLowBitsComeFromPPULatch(t *testing.T) {
+	bus := NewBus()
+
+	bus.CPUWrite(0x2003, 0x1F)
+	bus.CPUWrite(0x0000, 0x00)
+	bus.CPURead(0x0000, false)
+
+	if data := bus.CPURead(0x2002, false); data&0x1F != 0x1F {
+		t.Errorf("$2002 reads $%02X, want $1F in the low bits", data)
+	}
+
+	if data := bus.CPURead(0x2000, false); data != 0x1F {
+		t.Errorf("write-only $2000 reads $%02X, want the PPU latch $1F", data)
+	}
+}
+
+// Six frames bring every region's dot count back into phase with its CPU
+// divider, so the CPU cycle count comes out whole.
+func TestFrameTimingPerRegion(t *testing.T) {
+	const frames = 6
+
+	tests := []struct {
+		region      Region
+		masterTicks uint64
+		PPUDots     uint64
+		CPUCycles   uint64
+	}{
+		{REGION_NTSC, frames * 262 * 341 * 4, frames * 262 * 341, frames * 262 * 341 * 4 / 12},
+		{REGION_PAL, frames * 312 * 341 * 5, frames * 312 * 341, frames * 312 * 341 * 5 / 16},
+		{REGION_DENDY, frames * 312 * 341 * 5, frames * 312 * 341, frames * 312 * 341 * 5 / 15},
+	}
+
+	for _, test := range tests {
+		t.Run(test.region.String(), func(t *testing.T) {
+			bus := newTestBus(t, testINESImage(0, 1, 1, 0, nil))
+			bus.SetRegion(test.region)
+
+			for i := 0; i < frames; i++ {
+				bus.RunFrame()
+			}
+
+			PPUDots := bus.systemClockCounter
+			masterTicks := PPUDots * uint64(bus.timing.ppuClockDivider)
+
+			if masterTicks != test.masterTicks || PPUDots != test.PPUDots || bus.cpu.clockCount != test.CPUCycles {
+				t.Errorf("%d frames ran %d master ticks, %d dots, %d CPU cycles; want %d, %d, %d", frames,
+					masterTicks, PPUDots, bus.cpu.clockCount, test.masterTicks, test.PPUDots, test.CPUCycles)
+			}
+		})
+	}
+}
+
+// runOAMDMA runs STA $4014 from RAM, optionally behind a 3-cycle LDX zp that
+// flips the parity of its write cycle, and returns that cycle and the number
+// of cycles the CPU stays halted after it.
+func runOAMDMA(t *testing.T, shiftParity bool) (writeCycle uint64, stall uint64) {
+	bus := newTestBus(t, testINESImage(0, 1, 1, 0, nil))
+
+	for i := 0; i < PPU_OAM_SIZE; i++ {
+		bus.CPUWrite(0x0300+uint16(i), uint8(i)^0x5A)
+	}
+
+	program := []uint8{0xA9, 0x03} // LDA #$03
+	if shiftParity {
+		program = append(program, 0xA6, 0x00) // LDX $00
+	}
+	program = append(program, 0x8D, 0x14, 0x40, 0x4C, 0x00, 0x00) // STA $4014; JMP $0000
+	jumpTarget := len(program) - 3
+	program[len(program)-2] = uint8(jumpTarget)
+
+	for i, data := range program {
+		bus.CPUWrite(uint16(i), data)
+	}
+
+	for !bus.cpu.InstructionComplete() {
+		bus.Clock()
+	}
+	bus.cpu.programCounterReg = 0x0000
+
+	for !bus.oamDMA.active {
+		bus.Clock()
+	}
+	haltCycle := bus.cpu.clockCount - 1
+	writeCycle = haltCycle - 1
+
+	for bus.oamDMA.active {
+		bus.Clock()
+	}
+	stall = bus.cpu.clockCount - haltCycle
+
+	for i := 0; i < PPU_OAM_SIZE; i++ {
+		if bus.ppu.oam[i] != uint8(i)^0x5A {
+			t.Fatalf("OAM[%d] = $%02X, want $%02X", i, bus.ppu.oam[i], uint8(i)^0x5A)
+		}
+	}
+
+	return writeCycle, stall
+}
+
+// The DMA halts the CPU for one cycle, then reads on even cycles and writes
+// on odd ones: a $4014 write on an even cycle leaves the halt on an odd one
+// and stalls 513 cycles, a write on an odd cycle needs one more to align.
+func TestOAMDMAStall(t *testing.T) {
+	seen := map[uint64]bool{}
+
+	for _, shiftParity := range []bool{false, true} {
+		writeCycle, stall := runOAMDMA(t, shiftParity)
+
+		want := uint64(513)
+		if writeCycle%2 == 1 {
+			want = 514
+		}
+		if stall != want {
+			t.Errorf("$4014 written on cycle %d stalled %d cycles, want %d", writeCycle, stall, want)
+		}
+
+		seen[writeCycle%2] = true
+	}
+
+	if len(seen) != 2 {
+		t.Errorf("both programs wrote $4014 on cycles of the same parity")
+	}
+}
+
+// withIdleLoop points all three vectors of an iNES image at a JMP to itself
+// in the last 16 bytes of PRG ROM, so the CPU idles while a test drives the
+// PPU. The image must have no trainer.
+func withIdleLoop(image []uint8) []uint8 {
+	PRGEnd := INES_HEADER_SIZE + int(image[4])*PRG_BANK_SIZE
+
+	copy(image[PRGEnd-16:], []uint8{0x4C, 0xF0, 0xFF}) // $FFF0: JMP $FFF0
+	copy(image[PRGEnd-6:], []uint8{0xF0, 0xFF, 0xF0, 0xFF, 0xF0, 0xFF})
+
+	return image
+}
+
+func newTestBus(t *testing.T, image []uint8) *Bus {
+	cartridge, err := LoadCartridgeBytes(withIdleLoop(image))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bus := NewBus()
+	bus.InsertCartridge(cartridge)
+	bus.Reset()
+
+	return bus
+}
+
+// writeVRAM stores data from addr on through $2006/$2007.
+func writeVRAM(bus *Bus, addr uint16, data ...uint8) {
+	bus.CPURead(0x2002, false)
+	bus.CPUWrite(0x2006, uint8(addr>>8))
+	bus.CPUWrite(0x2006, uint8(addr))
+	for _, value := range data {
+		bus.CPUWrite(0x2007, value)
+	}
+}
+
+// startRendering resets the scroll and turns on the background with the
+// given $2000 value.
+func startRendering(bus *Bus, control uint8) {
+	bus.CPURead(0x2002, false)
+	bus.CPUWrite(0x2005, 0)
+	bus.CPUWrite(0x2005, 0)
+	bus.CPUWrite(0x2000, control)
+	bus.CPUWrite(0x2001, PPU_MASK_SHOW_BACKGROUND|PPU_MASK_BACKGROUND_LEFT)
+}
+
+// runUntilVBlank clocks the console to the first dot of the vblank scanline.
+func runUntilVBlank(bus *Bus) {
+	for bus.ppu.scanline != bus.ppu.timing.vblankScanline || bus.ppu.dot != 0 {
+		bus.Clock()
+	}
+}
+
+// runUntilIRQ clocks the console until the cartridge asserts its IRQ, for at
+// most two frames, and reports whether it did.
+func runUntilIRQ(bus *Bus) bool {
+	for dots := 0; dots < 2*PPU_DOTS_PER_SCANLINE*bus.ppu.timing.scanlinesPerFrame; dots++ {
+		if bus.cartridge.IRQState() {
+			return true
+		}
+		bus.Clock()
+	}
+	return false
+}
+
+// The palette written by writeTestPalette: background colors 1 and 2 of
+// palette 0.
+const (
+	testColor1 uint8 = 0x16
+	testColor2 uint8 = 0x2A
+)
+
+func writeTestPalette(bus *Bus) {
+	writeVRAM(bus, 0x3F00, 0x0F, testColor1, testColor2, 0x30)
+}
+
+// fillAlternatingCHR fills the CHR ROM of an iNES image with tiles of solid
+// color 2 in even 4KB banks and solid color 1 in odd ones.
+func fillAlternatingCHR(image []uint8) {
+	CHR := image[INES_HEADER_SIZE+int(image[4])*PRG_BANK_SIZE:]
+
+	for i := range CHR {
+		bank, plane := i/(CHR_BANK_SIZE/2), i&0x08
+		if (bank%2 == 0) == (plane != 0) {
+			CHR[i] = 0xFF
+		} else {
+			CHR[i] = 0x00
+		}
+	}
+}
+
+// checkColumns compares every pixel of rows firstRow on with the color
+// columnColor gives for its 8-pixel column.
+func checkColumns(t *testing.T, bus *Bus, firstRow int, columnColor func(column int) uint8) {
+	t.Helper()
+
+	frame := bus.Frame()
+	for y := firstRow; y < PPU_SCREEN_HEIGHT; y++ {
+		for x := 0; x < PPU_SCREEN_WIDTH; x++ {
+			if expected := columnColor(x / 8); frame[y][x] != expected {
+				t.Fatalf("pixel (%d, %d) is color $%02X, want $%02X", x, y, frame[y][x], expected)
+			}
+		}
+	}
+}
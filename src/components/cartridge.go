@@ -1,17 +1,52 @@
 package components
 
 import (
-	"os"
-	"unsafe"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	INES_TRAINER_SIZE = 512
+	INES_TRAINER_ADDR = 0x7000
+	NAMETABLE_SIZE    = 1024
+)
+
+// ErrTrainerWithoutPRGRAM is returned for an image with a trainer whose
+// board has no PRG RAM at $7000 to load it into.
+var ErrTrainerWithoutPRGRAM = errors.New("trainer needs PRG RAM at $7000")
+
+// Mirroring is the nametable layout a board wires up, named after the
+// direction the picture scrolls without seams.
+type Mirroring uint8
+
+const (
+	MIRROR_HORIZONTAL Mirroring = iota
+	MIRROR_VERTICAL
+	MIRROR_FOUR_SCREEN
+	MIRROR_SINGLE_SCREEN_LOW
+	MIRROR_SINGLE_SCREEN_HIGH
+	MIRROR_MAPPER_CONTROLLED
 )
 
 type Cartridge struct {
-	fileName  string
-	PRGMemory []uint8
-	CHRMemory []uint8
-	mapperID  uint8
-	PRGBanks  uint8
-	CHRBanks  uint8
+	fileName     string
+	archiveEntry string
+	header       CartridgeHeader
+	PRGMemory    []uint8
+	CHRMemory    []uint8
+	mapper       Mapper
+	mapperID     uint16
+	PRGBanks     int
+	CHRBanks     int
+	mirroring    Mirroring
+	hasBattery   bool
+	region       Region
+
+	// fourScreenVRAM backs the two nametables at $2800-$2FFF that boards
+	// with four-screen mirroring carry on top of the console's 2KB.
+	fourScreenVRAM [2 * NAMETABLE_SIZE]uint8
 }
 
 func NewCartridge() *Cartridge {
@@ -24,49 +59,283 @@ func NewCartridge() *Cartridge {
 	}
 }
 
-func (cart *Cartridge) openFile(fileName string) {
-	type FormatHeader struct {
-		name           [4]byte
-		PRG_ROM_chunks uint8
-		CHR_ROM_chunks uint8
-		mapper1        uint8
-		mapper2        uint8
-		PRG_RAM_size   uint8
-		TVsystem1      uint8
-		TVsystem2      uint8
-		unused         [5]byte
+// LoadCartridge reads an iNES file from disk. The file may also be a
+// compressed or archived ROM; see LoadCartridgeEntry.
+func LoadCartridge(fileName string) (*Cartridge, error) {
+	return LoadCartridgeEntry(fileName, "")
+}
+
+// ReadCartridge reads an iNES image from r. It stops after the CHR ROM, so
+// r may hold more data.
+func ReadCartridge(r io.Reader) (*Cartridge, error) {
+	cart := NewCartridge()
+
+	if err := cart.readINES(r); err != nil {
+		return nil, err
 	}
 
-	f, err := os.Open(fileName)
+	return cart, nil
+}
+
+// LoadCartridgeBytes reads an iNES image held in memory. The cartridge keeps
+// its own copy of the ROM, so data can be reused afterwards.
+func LoadCartridgeBytes(data []byte) (*Cartridge, error) {
+	return ReadCartridge(bytes.NewReader(data))
+}
+
+// readINES decodes the header, then the optional trainer, PRG ROM and CHR
+// ROM that follow it in that order.
+func (cart *Cartridge) readINES(r io.Reader) error {
+	raw := make([]uint8, INES_HEADER_SIZE)
 
+	if _, err := io.ReadFull(r, raw); err != nil {
+		return fmt.Errorf("reading header: %w", truncated(err))
+	}
+
+	header, err := ParseCartridgeHeader(raw)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	defer f.Close()
+	if header.PRGROMSize == 0 {
+		return fmt.Errorf("header declares no PRG ROM")
+	}
 
-	headerSize := make([]byte, int(unsafe.Sizeof(&FormatHeader{})))
+	// The NES 2.0 exponent form can describe ROMs smaller than a bank, or
+	// not a whole number of banks. Mappers work in whole banks, so the
+	// ROMs are padded with mirrors of themselves, as a board that leaves
+	// the upper address lines unconnected would see them.
+	PRGFileSize := header.PRGROMSize
+	CHRFileSize := header.CHRROMSize
+	header.PRGROMSize = wholeBanks(PRGFileSize, PRG_BANK_SIZE)
+	header.CHRROMSize = wholeBanks(CHRFileSize, CHR_BANK_SIZE)
 
-	header, err := f.Read(headerSize)
+	cart.header = header
+	cart.mapperID = header.Mapper
+	cart.PRGBanks = header.PRGROMSize / PRG_BANK_SIZE
+	cart.CHRBanks = header.CHRROMSize / CHR_BANK_SIZE
+	cart.mirroring = header.Mirroring
+	cart.hasBattery = header.HasBattery
+	cart.region = header.Region
 
+	cart.mapper, err = newMapper(header)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
+	if header.HasTrainer {
+		trainer := make([]uint8, INES_TRAINER_SIZE)
+		if _, err := io.ReadFull(r, trainer); err != nil {
+			return fmt.Errorf("reading trainer: %w", truncated(err))
+		}
+		if err := cart.loadTrainer(trainer); err != nil {
+			return err
+		}
+	}
+
+	cart.PRGMemory = make([]uint8, header.PRGROMSize)
+	if _, err := io.ReadFull(r, cart.PRGMemory[:PRGFileSize]); err != nil {
+		return fmt.Errorf("reading %d KB of PRG ROM: %w", PRGFileSize/1024, truncated(err))
+	}
+	mirrorPadding(cart.PRGMemory, PRGFileSize)
+
+	// Boards without CHR ROM carry CHR RAM in its place, 8KB unless a NES
+	// 2.0 header says otherwise.
+	if header.CHRROMSize == 0 {
+		CHRRAMSize := header.CHRRAMSize + header.CHRNVRAMSize
+		if CHRRAMSize == 0 {
+			CHRRAMSize = CHR_BANK_SIZE
+		}
+		cart.CHRMemory = make([]uint8, CHRRAMSize)
+		return nil
+	}
+
+	cart.CHRMemory = make([]uint8, header.CHRROMSize)
+	if _, err := io.ReadFull(r, cart.CHRMemory[:CHRFileSize]); err != nil {
+		return fmt.Errorf("reading %d KB of CHR ROM: %w", CHRFileSize/1024, truncated(err))
+	}
+	mirrorPadding(cart.CHRMemory, CHRFileSize)
+
+	return nil
+}
+
+// wholeBanks rounds size up to a multiple of bankSize.
+func wholeBanks(size int, bankSize int) int {
+	return (size + bankSize - 1) / bankSize * bankSize
+}
+
+// mirrorPadding repeats the first size bytes of memory over the rest.
+func mirrorPadding(memory []uint8, size int) {
+	for i := size; i < len(memory); i++ {
+		memory[i] = memory[i-size]
+	}
+}
+
+// loadTrainer copies the trainer to $7000-$71FF, where the code it holds
+// expects to run from. Boards without PRG RAM there have nowhere to keep it,
+// and the code would not run without it.
+func (cart *Cartridge) loadTrainer(trainer []uint8) error {
+	for i, data := range trainer {
+		var mappedAddr uint32
+		if !cart.mapper.CPUMapWrite(INES_TRAINER_ADDR+uint16(i), &mappedAddr, data) || mappedAddr != MAPPER_HANDLED {
+			return fmt.Errorf("mapper %d: %w", cart.mapperID, ErrTrainerWithoutPRGRAM)
+		}
+	}
+	return nil
+}
+
+// truncated turns the EOF errors of io.ReadFull into a message that says
+// what went wrong with the file.
+func truncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("file is truncated: %w", io.ErrUnexpectedEOF)
+	}
+	return err
+}
+
+func (cart *Cartridge) Header() CartridgeHeader {
+	return cart.header
+}
+
+func (cart *Cartridge) Mirroring() Mirroring {
+	if cart.mapper != nil {
+		return cart.mapper.Mirroring()
+	}
+	return cart.mirroring
 }
 
 func (cart *Cartridge) CPUWrite(addr uint16, data *uint8) {
+	var mappedAddr uint32
+
+	// Only PRG ROM fights the CPU for the bus: its chip enable follows A15
+	// and ignores R/W, so it drives every write from $8000 up.
+	isROMWrite := addr >= 0x8000
+	if conflicting, ok := cart.mapper.(busConflictMapper); ok && isROMWrite && conflicting.HasBusConflicts() {
+		if romData, driven := cart.CPURead(addr, true); driven {
+			conflicted := *data & romData
+			data = &conflicted
+		}
+	}
 
+	if cart.mapper == nil || !cart.mapper.CPUMapWrite(addr, &mappedAddr, *data) {
+		return
+	}
+
+	if mappedAddr != MAPPER_HANDLED {
+		cart.PRGMemory[mappedAddr] = *data
+	}
 }
 
-func (cart *Cartridge) CPURead(addr uint16, readOnly bool) uint8 {
-	return 0
+// CPURead reports false when the cartridge does not drive the data bus at
+// addr.
+func (cart *Cartridge) CPURead(addr uint16, readOnly bool) (uint8, bool) {
+	var mappedAddr uint32
+	var data uint8
+
+	if cart.mapper == nil || !cart.mapper.CPUMapRead(addr, &mappedAddr, &data, readOnly) {
+		return 0, false
+	}
+
+	if mappedAddr != MAPPER_HANDLED {
+		data = cart.PRGMemory[mappedAddr]
+	}
+
+	if observer, ok := cart.mapper.(cpuDataObserver); ok && !readOnly {
+		observer.CPUData(addr, data)
+	}
+
+	return data, true
+}
+
+// DrivenBits is the mask of data lines the cartridge drives when it answers
+// a read at addr.
+func (cart *Cartridge) DrivenBits(addr uint16) uint8 {
+	if driver, ok := cart.mapper.(partialBusDriver); ok {
+		return driver.DrivenBits(addr)
+	}
+	return 0xFF
+}
+
+// PPUWrite reports false when the write is left to the console's own VRAM.
+func (cart *Cartridge) PPUWrite(addr uint16, data *uint8) bool {
+	var mappedAddr uint32
+
+	if cart.mapper != nil && cart.mapper.PPUMapWrite(addr, &mappedAddr, *data) {
+		if mappedAddr != MAPPER_HANDLED {
+			cart.CHRMemory[mappedAddr] = *data
+		}
+		return true
+	}
+
+	if cart.isFourScreenVRAM(addr) {
+		cart.fourScreenVRAM[addr&0x07FF] = *data
+		return true
+	}
+
+	return false
 }
 
-func (cart *Cartridge) PPUWrite(addr uint16, data *uint8) {
+// PPURead reports false when the console's own VRAM answers at addr.
+func (cart *Cartridge) PPURead(addr uint16, readOnly bool) (uint8, bool) {
+	var mappedAddr uint32
+	var data uint8
 
+	if cart.mapper != nil && cart.mapper.PPUMapRead(addr, &mappedAddr, &data) {
+		if mappedAddr != MAPPER_HANDLED {
+			data = cart.CHRMemory[mappedAddr]
+		}
+		return data, true
+	}
+
+	if cart.isFourScreenVRAM(addr) {
+		return cart.fourScreenVRAM[addr&0x07FF], true
+	}
+
+	return 0, false
+}
+
+func (cart *Cartridge) isFourScreenVRAM(addr uint16) bool {
+	return cart.Mirroring() == MIRROR_FOUR_SCREEN && addr >= 0x2000 && addr <= 0x3EFF && addr&0x0800 != 0
 }
 
-func (cart *Cartridge) PPURead(addr uint16, readOnly bool) uint8 {
+// PPUAddress is told every address the PPU drives on its bus.
+func (cart *Cartridge) PPUAddress(addr uint16) {
+	if observer, ok := cart.mapper.(ppuAddressObserver); ok {
+		observer.PPUAddress(addr)
+	}
+}
+
+// CIRAMPage is the console nametable page a board selects for addr when
+// its mirroring is MIRROR_MAPPER_CONTROLLED.
+func (cart *Cartridge) CIRAMPage(addr uint16) int {
+	if selector, ok := cart.mapper.(ciramSelector); ok {
+		return selector.CIRAMPage(addr)
+	}
+	return int(addr>>10) & 0x01
+}
+
+// AudioSample is the output of the board's expansion audio, 0 for boards
+// without any.
+func (cart *Cartridge) AudioSample() float32 {
+	if audio, ok := cart.mapper.(expansionAudio); ok {
+		return audio.AudioSample()
+	}
 	return 0
 }
+
+// IRQState reports whether the board is pulling the CPU IRQ line low.
+func (cart *Cartridge) IRQState() bool {
+	return cart.mapper != nil && cart.mapper.IRQState()
+}
+
+func (cart *Cartridge) Reset() {
+	if cart.mapper != nil {
+		cart.mapper.Reset()
+	}
+}
+
+func (cart *Cartridge) Clock() {
+	if cart.mapper != nil {
+		cart.mapper.Clock()
+	}
+}
@@ -0,0 +1,280 @@
+package components
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// ARCHIVE_MAX_ENTRY_SIZE bounds how much is decompressed from a single
+// archive entry, well above the largest ROM a header can describe.
+const ARCHIVE_MAX_ENTRY_SIZE = 2 * NES20_MAX_ROM_SIZE
+
+var ErrNoROMInArchive = errors.New("archive holds no iNES ROM")
+
+// ErrUnsupportedFormat is returned for UNIF and FDS images. UNIF boards are
+// named rather than numbered and would need their own mapper table, and FDS
+// disks need the RAM adapter BIOS and a disk drive, neither of which the
+// emulator has.
+var ErrUnsupportedFormat = errors.New("unsupported ROM format")
+
+// LoadCartridgeEntry reads a ROM that may be stored in a .zip, .gz or .tar
+// file, or a .tar.gz. Inside archives it loads entryName, matched against the
+// full entry path or its base name, or the first .nes entry that starts with
+// the iNES signature when entryName is empty. Cartridge.ArchiveEntry reports
+// which entry was used.
+func LoadCartridgeEntry(fileName string, entryName string) (*Cartridge, error) {
+	data, err := os.ReadFile(fileName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := readCartridgeImage(data, path.Base(fileName), entryName)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fileName, err)
+	}
+
+	cart.fileName = fileName
+
+	return cart, nil
+}
+
+// ArchiveEntry is the name of the archive entry the ROM came from, or "" if
+// it was not loaded from an archive.
+func (cart *Cartridge) ArchiveEntry() string {
+	return cart.archiveEntry
+}
+
+// readCartridgeImage recognizes archives by their signature rather than by
+// extension, so misnamed files still load.
+func readCartridgeImage(data []byte, name string, entryName string) (*Cartridge, error) {
+	switch {
+	case isZip(data):
+		return readZipCartridge(data, entryName)
+	case isGzip(data):
+		return readGzipCartridge(data, name, entryName)
+	case isTar(data):
+		return readTarCartridge(data, entryName)
+	}
+
+	if err := checkFormat(data); err != nil {
+		return nil, err
+	}
+
+	return LoadCartridgeBytes(data)
+}
+
+// checkFormat recognizes the images LoadCartridgeBytes would only report as
+// not iNES, so the error says what the file is.
+func checkFormat(data []byte) error {
+	switch {
+	case bytes.HasPrefix(data, []byte("UNIF")):
+		return fmt.Errorf("UNIF image: %w", ErrUnsupportedFormat)
+	case bytes.HasPrefix(data, []byte("FDS\x1A")), bytes.HasPrefix(data, []byte("\x01*NINTENDO-HVC*")):
+		return fmt.Errorf("FDS image: %w", ErrUnsupportedFormat)
+	}
+	return nil
+}
+
+func isZip(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04")) || bytes.HasPrefix(data, []byte("PK\x05\x06"))
+}
+
+func isGzip(data []byte) bool {
+	return bytes.HasPrefix(data, []byte{0x1F, 0x8B})
+}
+
+func isTar(data []byte) bool {
+	return len(data) >= 262 && string(data[257:262]) == "ustar"
+}
+
+func readZipCartridge(data []byte, entryName string) (*Cartridge, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	skipped := ""
+
+	for _, entry := range archive.File {
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+
+		if !matchesEntry(entry.Name, entryName) {
+			if isUnsupportedEntry(entry.Name) {
+				skipped = entry.Name
+			}
+			continue
+		}
+
+		f, err := entry.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name, err)
+		}
+
+		cart, err := readArchiveEntry(f, entry.Name, entryName)
+		f.Close()
+
+		if err != errNotINESEntry {
+			return cart, err
+		}
+	}
+
+	return nil, missingEntry(entryName, skipped)
+}
+
+func readTarCartridge(data []byte, entryName string) (*Cartridge, error) {
+	archive := tar.NewReader(bytes.NewReader(data))
+	skipped := ""
+
+	for {
+		entry, err := archive.Next()
+
+		if err == io.EOF {
+			return nil, missingEntry(entryName, skipped)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if entry.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if !matchesEntry(entry.Name, entryName) {
+			if isUnsupportedEntry(entry.Name) {
+				skipped = entry.Name
+			}
+			continue
+		}
+
+		cart, err := readArchiveEntry(archive, entry.Name, entryName)
+
+		if err != errNotINESEntry {
+			return cart, err
+		}
+	}
+}
+
+// readGzipCartridge handles both a compressed ROM and a .tar.gz. A bare
+// compressed ROM is named after the original file name stored in the gzip
+// header, or after the file with its .gz suffix removed.
+func readGzipCartridge(data []byte, name string, entryName string) (*Cartridge, error) {
+	stream, err := gzip.NewReader(bytes.NewReader(data))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer stream.Close()
+
+	decompressed, err := readAllLimited(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	if isTar(decompressed) {
+		return readTarCartridge(decompressed, entryName)
+	}
+
+	innerName := stream.Name
+	if innerName == "" {
+		innerName = strings.TrimSuffix(name, path.Ext(name))
+	}
+
+	if err := checkFormat(decompressed); err != nil {
+		return nil, fmt.Errorf("%s: %w", innerName, err)
+	}
+
+	cart, err := LoadCartridgeBytes(decompressed)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", innerName, err)
+	}
+
+	cart.archiveEntry = innerName
+
+	return cart, nil
+}
+
+// errNotINESEntry makes the search for the first ROM skip an entry.
+var errNotINESEntry = errors.New("not an iNES image")
+
+// readArchiveEntry loads the entry called name. When no entry was asked for,
+// entries without the iNES signature are skipped with errNotINESEntry.
+func readArchiveEntry(r io.Reader, name string, entryName string) (*Cartridge, error) {
+	data, err := readAllLimited(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	if entryName == "" && !bytes.HasPrefix(data, []byte(INES_MAGIC)) {
+		return nil, errNotINESEntry
+	}
+
+	if err := checkFormat(data); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	cart, err := LoadCartridgeBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	cart.archiveEntry = name
+
+	return cart, nil
+}
+
+func readAllLimited(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, ARCHIVE_MAX_ENTRY_SIZE+1))
+
+	if err == nil && len(data) > ARCHIVE_MAX_ENTRY_SIZE {
+		err = fmt.Errorf("entry is larger than %d MB", ARCHIVE_MAX_ENTRY_SIZE/(1024*1024))
+	}
+
+	return data, err
+}
+
+// matchesEntry selects the requested entry, or any .nes file when none was
+// asked for.
+func matchesEntry(name string, entryName string) bool {
+	if entryName != "" {
+		return name == entryName || path.Base(name) == entryName
+	}
+
+	return strings.ToLower(path.Ext(name)) == ".nes"
+}
+
+// isUnsupportedEntry spots the UNIF and FDS images the search for a .nes
+// entry passes over, so an archive holding only those can say so.
+func isUnsupportedEntry(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".unf", ".unif", ".fds":
+		return true
+	}
+	return false
+}
+
+// missingEntry names the last UNIF or FDS entry skipped when no iNES ROM was
+// found in its place.
+func missingEntry(entryName string, skipped string) error {
+	if entryName != "" {
+		return fmt.Errorf("archive has no entry named %q", entryName)
+	}
+	if skipped != "" {
+		return fmt.Errorf("%s: %w", skipped, ErrUnsupportedFormat)
+	}
+	return ErrNoROMInArchive
+}
@@ -0,0 +1,160 @@
+package components
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"testing"
+)
+
+type archiveEntry struct {
+	name string
+	data []uint8
+}
+
+// testArchiveEntries puts a UNIF image and a misnamed text file ahead of the
+// real ROM, as collections of dumps commonly do.
+func testArchiveEntries() []archiveEntry {
+	return []archiveEntry{
+		{"game.unf", []uint8("UNIF\x07\x00\x00\x00")},
+		{"readme.nes", []uint8("not a ROM")},
+		{"roms/game.nes", testINESImage(0, 1, 1, 0, nil)},
+	}
+}
+
+func TestZipSkipsEntriesThatAreNotINES(t *testing.T) {
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+	for _, entry := range testArchiveEntries() {
+		w, err := archive.Create(entry.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(entry.data)
+	}
+	archive.Close()
+
+	cart, err := readCartridgeImage(buffer.Bytes(), "games.zip", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cart.ArchiveEntry() != "roms/game.nes" {
+		t.Errorf("loaded %q, want roms/game.nes", cart.ArchiveEntry())
+	}
+}
+
+func TestTarSkipsEntriesThatAreNotINES(t *testing.T) {
+	var buffer bytes.Buffer
+	archive := tar.NewWriter(&buffer)
+	for _, entry := range testArchiveEntries() {
+		archive.WriteHeader(&tar.Header{Name: entry.name, Mode: 0644, Size: int64(len(entry.data)), Typeflag: tar.TypeReg})
+		archive.Write(entry.data)
+	}
+	archive.Close()
+
+	cart, err := readCartridgeImage(buffer.Bytes(), "games.tar", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cart.ArchiveEntry() != "roms/game.nes" {
+		t.Errorf("loaded %q, want roms/game.nes", cart.ArchiveEntry())
+	}
+}
+
+func testZip(entries []archiveEntry) []byte {
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+	for _, entry := range entries {
+		w, _ := archive.Create(entry.name)
+		w.Write(entry.data)
+	}
+	archive.Close()
+
+	return buffer.Bytes()
+}
+
+func TestArchiveWithoutINESEntry(t *testing.T) {
+	data := testZip(testArchiveEntries()[1:2])
+
+	if _, err := readCartridgeImage(data, "games.zip", ""); err != ErrNoROMInArchive {
+		t.Errorf("got %v, want ErrNoROMInArchive", err)
+	}
+}
+
+func TestUNIFAndFDSAreRejected(t *testing.T) {
+	UNIF := testArchiveEntries()[0]
+	FDS := archiveEntry{"disk.fds", []uint8("FDS\x1A\x01")}
+
+	tests := []struct {
+		name      string
+		data      []byte
+		entryName string
+	}{
+		{"bare UNIF", UNIF.data, ""},
+		{"bare FDS", FDS.data, ""},
+		{"bare headerless FDS", []uint8("\x01*NINTENDO-HVC*"), ""},
+		{"archive of UNIF and text", testZip(testArchiveEntries()[:2]), ""},
+		{"named FDS entry", testZip([]archiveEntry{FDS}), "disk.fds"},
+	}
+
+	for _, test := range tests {
+		if _, err := readCartridgeImage(test.data, "game", test.entryName); !errors.Is(err, ErrUnsupportedFormat) {
+			t.Errorf("%s: got %v, want ErrUnsupportedFormat", test.name, err)
+		}
+	}
+}
+
+func testGzip(name string, data []byte) []byte {
+	var buffer bytes.Buffer
+	stream := gzip.NewWriter(&buffer)
+	stream.Name = name
+	stream.Write(data)
+	stream.Close()
+
+	return buffer.Bytes()
+}
+
+func TestGzipROM(t *testing.T) {
+	image := testINESImage(0, 1, 1, 0, nil)
+
+	tests := []struct {
+		storedName string
+		entry      string
+	}{
+		{"original.nes", "original.nes"},
+		{"", "game.nes"},
+	}
+
+	for _, test := range tests {
+		cart, err := readCartridgeImage(testGzip(test.storedName, image), "game.nes.gz", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cart.ArchiveEntry() != test.entry {
+			t.Errorf("stored name %q: loaded %q, want %q", test.storedName, cart.ArchiveEntry(), test.entry)
+		}
+		if data, _ := cart.CPURead(0x8001, true); data != 0x01 {
+			t.Errorf("stored name %q: $8001 reads $%02X, want $01", test.storedName, data)
+		}
+	}
+}
+
+func TestTarGzROM(t *testing.T) {
+	var buffer bytes.Buffer
+	archive := tar.NewWriter(&buffer)
+	for _, entry := range testArchiveEntries() {
+		archive.WriteHeader(&tar.Header{Name: entry.name, Mode: 0644, Size: int64(len(entry.data)), Typeflag: tar.TypeReg})
+		archive.Write(entry.data)
+	}
+	archive.Close()
+
+	cart, err := readCartridgeImage(testGzip("", buffer.Bytes()), "games.tar.gz", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cart.ArchiveEntry() != "roms/game.nes" {
+		t.Errorf("loaded %q, want roms/game.nes", cart.ArchiveEntry())
+	}
+}
@@ -0,0 +1,260 @@
+package components
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	INES_HEADER_SIZE   = 16
+	INES_MAGIC         = "NES\x1A"
+	INES_FLAG_VERTICAL = 0x01
+	INES_FLAG_BATTERY  = 0x02
+	INES_FLAG_TRAINER  = 0x04
+	INES_FLAG_4SCREEN  = 0x08
+	PRG_BANK_SIZE      = 16 * 1024
+	PRG_BANK_SIZE_8K   = 8 * 1024
+	CHR_BANK_SIZE      = 8 * 1024
+	PRG_RAM_BANK_SIZE  = 8 * 1024
+
+	// The plain NES 2.0 size form tops out just under 64MB; the exponent
+	// form is held to the same limit.
+	NES20_MAX_ROM_SIZE = 64 * 1024 * 1024
+)
+
+var ErrNotINES = errors.New("not an iNES file")
+
+// ConsoleType is the hardware a NES 2.0 image targets, from bits 0-1 of
+// byte 7. iNES images only distinguish the Vs. System and PlayChoice-10.
+type ConsoleType uint8
+
+const (
+	CONSOLE_NES ConsoleType = iota
+	CONSOLE_VS_SYSTEM
+	CONSOLE_PLAYCHOICE
+	CONSOLE_EXTENDED
+)
+
+// FormatHeader is the 16-byte iNES header as stored in the file.
+type FormatHeader struct {
+	name           [4]byte
+	PRG_ROM_chunks uint8
+	CHR_ROM_chunks uint8
+	mapper1        uint8
+	mapper2        uint8
+	PRG_RAM_size   uint8
+	TVsystem1      uint8
+	TVsystem2      uint8
+	unused         [5]byte
+}
+
+func decodeFormatHeader(raw []uint8) FormatHeader {
+	header := FormatHeader{
+		PRG_ROM_chunks: raw[4],
+		CHR_ROM_chunks: raw[5],
+		mapper1:        raw[6],
+		mapper2:        raw[7],
+		PRG_RAM_size:   raw[8],
+		TVsystem1:      raw[9],
+		TVsystem2:      raw[10],
+	}
+	copy(header.name[:], raw[0:4])
+	copy(header.unused[:], raw[11:16])
+
+	return header
+}
+
+// CartridgeHeader is the decoded header of an iNES or NES 2.0 image. Sizes
+// are in bytes; the RAM sizes are 0 when the header does not declare any.
+type CartridgeHeader struct {
+	IsNES20   bool
+	Mapper    uint16
+	Submapper uint8
+
+	PRGROMSize   int
+	CHRROMSize   int
+	PRGRAMSize   int
+	PRGNVRAMSize int
+	CHRRAMSize   int
+	CHRNVRAMSize int
+
+	Mirroring  Mirroring
+	HasBattery bool
+	HasTrainer bool
+
+	// Region is the CPU/PPU timing. MultiRegion images run on any console
+	// and report REGION_NTSC.
+	Region      Region
+	MultiRegion bool
+
+	ConsoleType ConsoleType
+	// VsPPUType and VsHardwareType come from byte 13 of Vs. System images,
+	// ExtendedConsoleType from the same byte when ConsoleType is
+	// CONSOLE_EXTENDED.
+	VsPPUType           uint8
+	VsHardwareType      uint8
+	ExtendedConsoleType uint8
+
+	MiscROMs        uint8
+	ExpansionDevice uint8
+}
+
+// ParseCartridgeHeader decodes the 16 header bytes at the start of an image.
+func ParseCartridgeHeader(raw []uint8) (CartridgeHeader, error) {
+	var header CartridgeHeader
+
+	if len(raw) < INES_HEADER_SIZE {
+		return header, fmt.Errorf("header is %d bytes, want %d", len(raw), INES_HEADER_SIZE)
+	}
+
+	if string(raw[0:4]) != INES_MAGIC {
+		return header, ErrNotINES
+	}
+
+	// Headers written by old tools carry garbage such as "DiskDude!" in
+	// bytes 7-15. An iNES header must have zeros in bytes 12-15; when it
+	// does not, only the first 7 bytes can be trusted.
+	isNES20 := raw[7]&0x0C == 0x08
+	if !isNES20 && (raw[12] != 0 || raw[13] != 0 || raw[14] != 0 || raw[15] != 0) {
+		cleaned := make([]uint8, INES_HEADER_SIZE)
+		copy(cleaned, raw[0:7])
+		raw = cleaned
+	}
+
+	format := decodeFormatHeader(raw)
+
+	header.IsNES20 = isNES20
+	header.HasBattery = format.mapper1&INES_FLAG_BATTERY != 0
+	header.HasTrainer = format.mapper1&INES_FLAG_TRAINER != 0
+	header.ConsoleType = ConsoleType(format.mapper2 & 0x03)
+	header.Region = regionFromHeader(raw)
+
+	switch {
+	case format.mapper1&INES_FLAG_4SCREEN != 0:
+		header.Mirroring = MIRROR_FOUR_SCREEN
+	case format.mapper1&INES_FLAG_VERTICAL != 0:
+		header.Mirroring = MIRROR_VERTICAL
+	default:
+		header.Mirroring = MIRROR_HORIZONTAL
+	}
+
+	var err error
+	if header.IsNES20 {
+		err = header.decodeNES20(raw)
+	} else {
+		header.decodeINES(format)
+	}
+
+	return header, err
+}
+
+func (this *CartridgeHeader) decodeINES(format FormatHeader) {
+	// iNES cannot express an extended console; bit 1 means PlayChoice-10.
+	if this.ConsoleType == CONSOLE_EXTENDED {
+		this.ConsoleType = CONSOLE_VS_SYSTEM
+	}
+
+	this.Mapper = uint16(format.mapper2&0xF0 | format.mapper1>>4)
+	this.PRGROMSize = int(format.PRG_ROM_chunks) * PRG_BANK_SIZE
+	this.CHRROMSize = int(format.CHR_ROM_chunks) * CHR_BANK_SIZE
+
+	// A PRG RAM size of 0 means 8KB for compatibility with old images.
+	PRGRAMSize := int(format.PRG_RAM_size) * PRG_RAM_BANK_SIZE
+	if PRGRAMSize == 0 {
+		PRGRAMSize = PRG_RAM_BANK_SIZE
+	}
+
+	if this.HasBattery {
+		this.PRGNVRAMSize = PRGRAMSize
+	} else {
+		this.PRGRAMSize = PRGRAMSize
+	}
+
+	if this.CHRROMSize == 0 {
+		this.CHRRAMSize = CHR_BANK_SIZE
+	}
+}
+
+// BoardPRGRAMSize is the PRG RAM a board is given, in bytes. NES 2.0 headers
+// declare it, and so does an iNES header asking for more than 8KB. Otherwise
+// the header cannot tell: iNES reads a size of 0 as 8KB, so every image
+// seems to have it. Boards then get iNESSize, what their usual variants
+// carry, or 0 for boards whose games rarely have any. A battery or trainer
+// shows there is RAM, so those images get at least the 8KB the header
+// reports.
+func (this CartridgeHeader) BoardPRGRAMSize(iNESSize int) int {
+	declared := this.PRGRAMSize + this.PRGNVRAMSize
+
+	if this.IsNES20 || declared > PRG_RAM_BANK_SIZE {
+		return declared
+	}
+
+	if (this.HasBattery || this.HasTrainer) && iNESSize < declared {
+		return declared
+	}
+
+	return iNESSize
+}
+
+func (this *CartridgeHeader) decodeNES20(raw []uint8) error {
+	this.Mapper = uint16(raw[8]&0x0F)<<8 | uint16(raw[7]&0xF0) | uint16(raw[6]>>4)
+	this.Submapper = raw[8] >> 4
+
+	var err error
+
+	this.PRGROMSize, err = nes20ROMSize(raw[4], raw[9]&0x0F, PRG_BANK_SIZE)
+	if err != nil {
+		return fmt.Errorf("PRG ROM size: %w", err)
+	}
+
+	this.CHRROMSize, err = nes20ROMSize(raw[5], raw[9]>>4, CHR_BANK_SIZE)
+	if err != nil {
+		return fmt.Errorf("CHR ROM size: %w", err)
+	}
+
+	this.PRGRAMSize = nes20RAMSize(raw[10] & 0x0F)
+	this.PRGNVRAMSize = nes20RAMSize(raw[10] >> 4)
+	this.CHRRAMSize = nes20RAMSize(raw[11] & 0x0F)
+	this.CHRNVRAMSize = nes20RAMSize(raw[11] >> 4)
+
+	this.MultiRegion = raw[12]&0x03 == 2
+
+	switch this.ConsoleType {
+	case CONSOLE_VS_SYSTEM:
+		this.VsPPUType = raw[13] & 0x0F
+		this.VsHardwareType = raw[13] >> 4
+	case CONSOLE_EXTENDED:
+		this.ExtendedConsoleType = raw[13] & 0x0F
+	}
+
+	this.MiscROMs = raw[14] & 0x03
+	this.ExpansionDevice = raw[15] & 0x3F
+
+	return nil
+}
+
+// nes20ROMSize combines the size byte from bytes 4-5 with its most
+// significant nibble from byte 9. A nibble of $F switches to the
+// exponent-multiplier form EEEEEEMM: 2^E * (MM*2+1) bytes.
+func nes20ROMSize(lsb uint8, msb uint8, bankSize int) (int, error) {
+	if msb != 0x0F {
+		return (int(msb)<<8 | int(lsb)) * bankSize, nil
+	}
+
+	exponent := lsb >> 2
+	multiplier := int(lsb&0x03)*2 + 1
+
+	if exponent >= 32 || (1<<exponent)*multiplier > NES20_MAX_ROM_SIZE {
+		return 0, fmt.Errorf("2^%d * %d bytes is too large", exponent, multiplier)
+	}
+
+	return (1 << exponent) * multiplier, nil
+}
+
+// nes20RAMSize decodes a shift count: 0 means none, otherwise 64 << shift.
+func nes20RAMSize(shift uint8) int {
+	if shift == 0 {
+		return 0
+	}
+	return 64 << shift
+}
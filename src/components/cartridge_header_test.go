@@ -0,0 +1,98 @@
+package components
+
+import "testing"
+
+func TestHeaderROMSizes(t *testing.T) {
+	tests := []struct {
+		name    string
+		bytes   [3]uint8 // bytes 4, 5 and 9
+		nes20   bool
+		PRGSize int
+		CHRSize int
+	}{
+		{"iNES", [3]uint8{2, 1, 0}, false, 2 * PRG_BANK_SIZE, CHR_BANK_SIZE},
+		{"iNES 255 banks", [3]uint8{0xFF, 0xFF, 0}, false, 255 * PRG_BANK_SIZE, 255 * CHR_BANK_SIZE},
+		{"NES 2.0 plain", [3]uint8{0x00, 0x02, 0x01}, true, 256 * PRG_BANK_SIZE, 2 * CHR_BANK_SIZE},
+		{"NES 2.0 plain high nibbles", [3]uint8{0x10, 0x00, 0x21}, true, 0x110 * PRG_BANK_SIZE, 0x200 * CHR_BANK_SIZE},
+		{"NES 2.0 exponent 8KB PRG", [3]uint8{0x34, 0x00, 0x0F}, true, 8 * 1024, 0},
+		{"NES 2.0 exponent 24KB PRG", [3]uint8{0x35, 0x00, 0x0F}, true, 24 * 1024, 0},
+		{"NES 2.0 exponent 4KB CHR", [3]uint8{0x01, 0x30, 0xF0}, true, PRG_BANK_SIZE, 4 * 1024},
+	}
+
+	for _, test := range tests {
+		raw := make([]uint8, INES_HEADER_SIZE)
+		copy(raw, INES_MAGIC)
+		raw[4] = test.bytes[0]
+		raw[5] = test.bytes[1]
+		raw[9] = test.bytes[2]
+		if test.nes20 {
+			raw[7] = 0x08
+		}
+
+		header, err := ParseCartridgeHeader(raw)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if header.IsNES20 != test.nes20 {
+			t.Errorf("%s: IsNES20 is %v", test.name, header.IsNES20)
+		}
+		if header.PRGROMSize != test.PRGSize || header.CHRROMSize != test.CHRSize {
+			t.Errorf("%s: PRG %d, CHR %d; want PRG %d, CHR %d",
+				test.name, header.PRGROMSize, header.CHRROMSize, test.PRGSize, test.CHRSize)
+		}
+	}
+}
+
+func TestHeaderRejectsOversizedExponent(t *testing.T) {
+	raw := make([]uint8, INES_HEADER_SIZE)
+	copy(raw, INES_MAGIC)
+	raw[4] = 0xFF // 2^63 * 7
+	raw[7] = 0x08
+	raw[9] = 0x0F
+
+	if _, err := ParseCartridgeHeader(raw); err == nil {
+		t.Error("2^63 * 7 byte PRG ROM was accepted")
+	}
+}
+
+func TestBoardPRGRAMSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		flags6   uint8
+		RAMByte  uint8 // byte 8 of iNES, byte 10 of NES 2.0
+		nes20    bool
+		iNESSize int
+		size     int
+	}{
+		{"iNES without RAM on the board", 0, 0, false, 0, 0},
+		{"iNES battery", INES_FLAG_BATTERY, 0, false, 0, PRG_RAM_BANK_SIZE},
+		{"iNES trainer", INES_FLAG_TRAINER, 0, false, 0, PRG_RAM_BANK_SIZE},
+		{"iNES board default", 0, 0, false, PRG_RAM_BANK_SIZE, PRG_RAM_BANK_SIZE},
+		{"iNES battery on a larger board", INES_FLAG_BATTERY, 0, false, 64 * 1024, 64 * 1024},
+		{"iNES declaring 32KB", 0, 4, false, PRG_RAM_BANK_SIZE, 32 * 1024},
+		{"NES 2.0 none", INES_FLAG_BATTERY, 0x00, true, PRG_RAM_BANK_SIZE, 0},
+		{"NES 2.0 8KB RAM and 8KB NVRAM", 0, 0x77, true, 0, 16 * 1024},
+	}
+
+	for _, test := range tests {
+		raw := make([]uint8, INES_HEADER_SIZE)
+		copy(raw, INES_MAGIC)
+		raw[4] = 1
+		raw[6] = test.flags6
+		if test.nes20 {
+			raw[7] = 0x08
+			raw[10] = test.RAMByte
+		} else {
+			raw[8] = test.RAMByte
+		}
+
+		header, err := ParseCartridgeHeader(raw)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if size := header.BoardPRGRAMSize(test.iNESSize); size != test.size {
+			t.Errorf("%s: %d bytes of PRG RAM, want %d", test.name, size, test.size)
+		}
+	}
+}
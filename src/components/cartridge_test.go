@@ -0,0 +1,213 @@
+package components
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// testINESImage builds an iNES 1.0 image with PRG and CHR ROM filled with
+// their offset, and a trainer when one is given.
+func testINESImage(mapperID uint8, PRGBanks uint8, CHRBanks uint8, flags6 uint8, trainer []uint8) []uint8 {
+	image := make([]uint8, INES_HEADER_SIZE)
+	copy(image, INES_MAGIC)
+	image[4] = PRGBanks
+	image[5] = CHRBanks
+	image[6] = mapperID<<4 | flags6
+	image[7] = mapperID & 0xF0
+
+	if trainer != nil {
+		image[6] |= INES_FLAG_TRAINER
+		image = append(image, trainer...)
+	}
+
+	for i := 0; i < int(PRGBanks)*PRG_BANK_SIZE+int(CHRBanks)*CHR_BANK_SIZE; i++ {
+		image = append(image, uint8(i))
+	}
+
+	return image
+}
+
+func TestTruncatedImageIsAnError(t *testing.T) {
+	image := testINESImage(0, 2, 1, 0, nil)
+
+	for _, size := range []int{INES_HEADER_SIZE - 1, INES_HEADER_SIZE + PRG_BANK_SIZE, len(image) - 1} {
+		err := NewCartridge().readINES(bytes.NewReader(image[:size]))
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("%d-byte image returned %v, want io.ErrUnexpectedEOF", size, err)
+		}
+	}
+
+	if err := NewCartridge().readINES(bytes.NewReader(image)); err != nil {
+		t.Errorf("complete image returned %v", err)
+	}
+}
+
+func TestTrainerIsLoadedAt7000(t *testing.T) {
+	trainer := make([]uint8, INES_TRAINER_SIZE)
+	for i := range trainer {
+		trainer[i] = uint8(i) ^ 0xA5
+	}
+
+	for _, mapperID := range []uint8{0, 1, 4} {
+		cart, err := LoadCartridgeBytes(testINESImage(mapperID, 2, 1, 0, trainer))
+		if err != nil {
+			t.Fatalf("mapper %d: %v", mapperID, err)
+		}
+
+		for i, expected := range trainer {
+			if data, driven := cart.CPURead(INES_TRAINER_ADDR+uint16(i), true); !driven || data != expected {
+				t.Fatalf("mapper %d: $%04X reads $%02X (driven %v), want $%02X", mapperID, INES_TRAINER_ADDR+i, data, driven, expected)
+			}
+		}
+
+		// The PRG ROM starts right after the trainer.
+		if data, _ := cart.CPURead(0x8001, true); data != 0x01 {
+			t.Errorf("mapper %d: $8001 reads $%02X, want $01", mapperID, data)
+		}
+	}
+}
+
+func TestTrainerWithoutPRGRAMIsRejected(t *testing.T) {
+	// UxROM has nothing mapped at $6000-$7FFF.
+	_, err := LoadCartridgeBytes(testINESImage(2, 2, 0, 0, make([]uint8, INES_TRAINER_SIZE)))
+	if !errors.Is(err, ErrTrainerWithoutPRGRAM) {
+		t.Errorf("loading a UxROM image with a trainer returned %v, want ErrTrainerWithoutPRGRAM", err)
+	}
+}
+
+// testNES20Image builds an NES 2.0 image whose ROM sizes come from header
+// bytes 4, 5 and 9, with PRG and CHR ROM filled with their offset.
+func testNES20Image(mapperID uint8, PRGSizeByte uint8, CHRSizeByte uint8, sizeMSB uint8, PRGSize int, CHRSize int) []uint8 {
+	image := make([]uint8, INES_HEADER_SIZE)
+	copy(image, INES_MAGIC)
+	image[4] = PRGSizeByte
+	image[5] = CHRSizeByte
+	image[6] = mapperID << 4
+	image[7] = mapperID&0xF0 | 0x08
+	image[9] = sizeMSB
+	image[11] = 0x07 // 8KB CHR RAM
+
+	for i := 0; i < PRGSize+CHRSize; i++ {
+		image = append(image, uint8(i))
+	}
+
+	return image
+}
+
+// testBankedCartridge loads an NES 2.0 image whose 16KB PRG banks and 4KB CHR
+// banks start with their own number. RAMShift is the PRG RAM size byte:
+// 64 << RAMShift bytes, or none when 0.
+func testBankedCartridge(t *testing.T, mapperID uint8, submapper uint8, PRGBanks int, CHRBanks int, RAMShift uint8) *Cartridge {
+	image := make([]uint8, INES_HEADER_SIZE, INES_HEADER_SIZE+PRGBanks*PRG_BANK_SIZE+CHRBanks*CHR_BANK_SIZE)
+	copy(image, INES_MAGIC)
+	image[4] = uint8(PRGBanks)
+	image[5] = uint8(CHRBanks)
+	image[6] = mapperID << 4
+	image[7] = mapperID&0xF0 | 0x08
+	image[8] = submapper << 4
+	image[9] = uint8(PRGBanks>>8) & 0x0F
+	image[10] = RAMShift
+	if CHRBanks == 0 {
+		image[11] = 0x07 // 8KB CHR RAM
+	}
+
+	PRG := make([]uint8, PRGBanks*PRG_BANK_SIZE)
+	for bank := 0; bank < PRGBanks; bank++ {
+		PRG[bank*PRG_BANK_SIZE] = uint8(bank)
+	}
+
+	CHR := make([]uint8, CHRBanks*CHR_BANK_SIZE)
+	for bank := 0; bank < CHRBanks*2; bank++ {
+		CHR[bank*CHR_BANK_SIZE/2] = uint8(bank)
+	}
+
+	cart, err := LoadCartridgeBytes(append(append(image, PRG...), CHR...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cart
+}
+
+// readPRGBank and readCHRBank return the number a testBankedCartridge bank
+// starts with, when addr is the start of a bank.
+func readPRGBank(cart *Cartridge, addr uint16) uint8 {
+	data, _ := cart.CPURead(addr, true)
+	return data
+}
+
+func readCHRBank(cart *Cartridge, addr uint16) uint8 {
+	data, _ := cart.PPURead(addr, true)
+	return data
+}
+
+func TestPartialPRGBankIsMirrored(t *testing.T) {
+	// 2^13 * 1 = 8KB of PRG ROM on NROM.
+	cart, err := LoadCartridgeBytes(testNES20Image(0, 0x34, 0, 0x0F, 8*1024, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cart.PRGBanks != 1 {
+		t.Errorf("PRGBanks is %d, want 1", cart.PRGBanks)
+	}
+
+	for _, addr := range []uint16{0x8000, 0xA000, 0xC000, 0xE000} {
+		if data, _ := cart.CPURead(addr+0x0123, true); data != 0x23 {
+			t.Errorf("$%04X reads $%02X, want $23", addr+0x0123, data)
+		}
+	}
+}
+
+func TestBankCountsAboveAByte(t *testing.T) {
+	// 256 16KB banks on UxROM: the fixed bank at $C000 is the last one.
+	const PRGBanks = 256
+	image := testNES20Image(2, 0x00, 0, 0x01, PRGBanks*PRG_BANK_SIZE, 0)
+	image[INES_HEADER_SIZE+(PRGBanks-1)*PRG_BANK_SIZE] = 0xAB
+
+	cart, err := LoadCartridgeBytes(image)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cart.PRGBanks != PRGBanks {
+		t.Errorf("PRGBanks is %d, want %d", cart.PRGBanks, PRGBanks)
+	}
+	if data, _ := cart.CPURead(0xC000, true); data != 0xAB {
+		t.Errorf("$C000 reads $%02X, want $AB", data)
+	}
+}
+
+// With submapper 2 the byte the ROM drives at the written address is ANDed
+// into the value; the banks are all zero past their first byte, so writes
+// to $8001 land as 0.
+func TestBusConflictsANDWithROM(t *testing.T) {
+	tests := []struct {
+		mapperID uint8
+		register func(Mapper) uint8
+	}{
+		{2, func(mapper Mapper) uint8 { return mapper.(*mapper002).PRGBank }},
+		{3, func(mapper Mapper) uint8 { return mapper.(*mapper003).CHRBank }},
+		{7, func(mapper Mapper) uint8 { return mapper.(*mapper007).bankRegister }},
+	}
+
+	for _, test := range tests {
+		for _, submapper := range []uint8{BUS_CONFLICTS_SUBMAPPER_NONE, BUS_CONFLICTS_SUBMAPPER_AND} {
+			cart := testBankedCartridge(t, test.mapperID, submapper, 4, 1, 0)
+
+			expected := uint8(0x03)
+			if submapper == BUS_CONFLICTS_SUBMAPPER_AND {
+				expected = 0
+			}
+
+			data := uint8(0x03)
+			cart.CPUWrite(0x8001, &data)
+
+			if register := test.register(cart.mapper); register != expected {
+				t.Errorf("mapper %d, submapper %d: register is $%02X, want $%02X", test.mapperID, submapper, register, expected)
+			}
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package components
+
+// Standard controller buttons, in the order the shift register reports them.
+const (
+	BUTTON_A      uint8 = 1 << 0
+	BUTTON_B      uint8 = 1 << 1
+	BUTTON_SELECT uint8 = 1 << 2
+	BUTTON_START  uint8 = 1 << 3
+	BUTTON_UP     uint8 = 1 << 4
+	BUTTON_DOWN   uint8 = 1 << 5
+	BUTTON_LEFT   uint8 = 1 << 6
+	BUTTON_RIGHT  uint8 = 1 << 7
+)
+
+type Controller struct {
+	buttons       uint8
+	shiftRegister uint8
+	strobe        bool
+}
+
+func (this *Controller) SetButtons(buttons uint8) {
+	this.buttons = buttons
+}
+
+// Write receives bit 0 of $4016. While the strobe is high the shift register
+// keeps reloading the button state.
+func (this *Controller) Write(data uint8) {
+	this.strobe = data&0x01 != 0
+
+	if this.strobe {
+		this.shiftRegister = this.buttons
+	}
+}
+
+// Read returns the next button in bit 0. After all eight buttons an official
+// controller keeps returning 1.
+func (this *Controller) Read(readOnly bool) uint8 {
+	if this.strobe {
+		return this.buttons & 0x01
+	}
+
+	data := this.shiftRegister & 0x01
+
+	if !readOnly {
+		this.shiftRegister = (this.shiftRegister >> 1) | 0x80
+	}
+
+	return data
+}
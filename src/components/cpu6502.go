@@ -1,7 +1,5 @@
 package components
 
-import "reflect"
-
 type Flag uint8
 
 // FLAGS
@@ -18,15 +16,54 @@ const (
 
 const STACK_HARCODED_ADDR uint16 = 0x0100
 
+// CPUBus is everything CPU6502 needs from the machine around it: the NES Bus,
+// a FlatMemory or any other 6502 system. Reads with readOnly set come from
+// debugging tools and must not have side effects.
+type CPUBus interface {
+	CPURead(addr uint16, readOnly bool) uint8
+	CPUWrite(addr uint16, data uint8)
+}
+
+type addressingMode uint8
+
+const (
+	imp addressingMode = iota // implied
+	acc                       // accumulator, the implied operand of shifts and rotates
+	imm                       // immediate
+	zp0                       // zero page
+	zpx                       // zero page, X
+	zpy                       // zero page, Y
+	rel                       // relative
+	abs                       // absolute
+	abx                       // absolute, X
+	aby                       // absolute, Y
+	ind                       // indirect
+	izx                       // indexed indirect, (zero page, X)
+	izy                       // indirect indexed, (zero page), Y
+)
+
+// operandLength is the number of bytes following the opcode.
+func (this addressingMode) operandLength() uint16 {
+	switch this {
+	case imp, acc:
+		return 0
+	case abs, abx, aby, ind:
+		return 2
+	default:
+		return 1
+	}
+}
+
 type instruction struct {
 	name                        string
-	operation                   func() uint8
-	addressingMode              func() uint8
+	operation                   func(*CPU6502) uint8
+	addressingMode              addressingMode
 	requiredAmountOfClockCycles uint8
+	unofficial                  bool
 }
 
 type CPU6502 struct {
-	bus                 *Bus
+	bus                 CPUBus
 	accumulatorReg      uint8
 	xReg                uint8
 	yReg                uint8
@@ -37,39 +74,68 @@ type CPU6502 struct {
 	absoluteAddress     uint16
 	relativeAddress     uint16
 	opCode              uint8
+	addressingMode      addressingMode
 	amountOfClockCycles uint8
-	lookup              []instruction
+	clockCount          uint64
+	hasDecimalMode      bool
+	cycleAccurate       bool
+	nextCycleAccurate   bool
+	instructionStep     uint8
+	operandLatched      bool
+	pageCrossed         bool
+	branchTaken         bool
+	uncorrectedAddress  uint16
+	pointerAddress      uint16
+
+	irqLine                bool
+	irqAsserted            bool
+	nmiEdge                bool
+	nmiPending             bool
+	interruptDisableAtPoll bool
+	interruptPollHistory   uint8
+	interruptRequested     bool
+	hardwareInterrupt      bool
+	// takenBranchOnSamePage is set in the instruction-level mode after a
+	// 3-cycle branch, which polls one cycle earlier than other instructions.
+	takenBranchOnSamePage bool
+}
+
+// lookup is indexed by opcode. Operations are method expressions, so the
+// table is plain data shared by every CPU6502.
+var lookup = [256]instruction{
+	{"BRK", (*CPU6502).BRK, imm, 7, false}, {"ORA", (*CPU6502).ORA, izx, 6, false}, {"JAM", (*CPU6502).JAM, imp, 2, true}, {"SLO", (*CPU6502).SLO, izx, 8, true}, {"NOP", (*CPU6502).NOP, zp0, 3, true}, {"ORA", (*CPU6502).ORA, zp0, 3, false}, {"ASL", (*CPU6502).ASL, zp0, 5, false}, {"SLO", (*CPU6502).SLO, zp0, 5, true}, {"PHP", (*CPU6502).PHP, imp, 3, false}, {"ORA", (*CPU6502).ORA, imm, 2, false}, {"ASL", (*CPU6502).ASL, acc, 2, false}, {"ANC", (*CPU6502).ANC, imm, 2, true}, {"NOP", (*CPU6502).NOP, abs, 4, true}, {"ORA", (*CPU6502).ORA, abs, 4, false}, {"ASL", (*CPU6502).ASL, abs, 6, false}, {"SLO", (*CPU6502).SLO, abs, 6, true},
+	{"BPL", (*CPU6502).BPL, rel, 2, false}, {"ORA", (*CPU6502).ORA, izy, 5, false}, {"JAM", (*CPU6502).JAM, imp, 2, true}, {"SLO", (*CPU6502).SLO, izy, 8, true}, {"NOP", (*CPU6502).NOP, zpx, 4, true}, {"ORA", (*CPU6502).ORA, zpx, 4, false}, {"ASL", (*CPU6502).ASL, zpx, 6, false}, {"SLO", (*CPU6502).SLO, zpx, 6, true}, {"CLC", (*CPU6502).CLC, imp, 2, false}, {"ORA", (*CPU6502).ORA, aby, 4, false}, {"NOP", (*CPU6502).NOP, imp, 2, true}, {"SLO", (*CPU6502).SLO, aby, 7, true}, {"NOP", (*CPU6502).NOP, abx, 4, true}, {"ORA", (*CPU6502).ORA, abx, 4, false}, {"ASL", (*CPU6502).ASL, abx, 7, false}, {"SLO", (*CPU6502).SLO, abx, 7, true},
+	{"JSR", (*CPU6502).JSR, abs, 6, false}, {"AND", (*CPU6502).AND, izx, 6, false}, {"JAM", (*CPU6502).JAM, imp, 2, true}, {"RLA", (*CPU6502).RLA, izx, 8, true}, {"BIT", (*CPU6502).BIT, zp0, 3, false}, {"AND", (*CPU6502).AND, zp0, 3, false}, {"ROL", (*CPU6502).ROL, zp0, 5, false}, {"RLA", (*CPU6502).RLA, zp0, 5, true}, {"PLP", (*CPU6502).PLP, imp, 4, false}, {"AND", (*CPU6502).AND, imm, 2, false}, {"ROL", (*CPU6502).ROL, acc, 2, false}, {"ANC", (*CPU6502).ANC, imm, 2, true}, {"BIT", (*CPU6502).BIT, abs, 4, false}, {"AND", (*CPU6502).AND, abs, 4, false}, {"ROL", (*CPU6502).ROL, abs, 6, false}, {"RLA", (*CPU6502).RLA, abs, 6, true},
+	{"BMI", (*CPU6502).BMI, rel, 2, false}, {"AND", (*CPU6502).AND, izy, 5, false}, {"JAM", (*CPU6502).JAM, imp, 2, true}, {"RLA", (*CPU6502).RLA, izy, 8, true}, {"NOP", (*CPU6502).NOP, zpx, 4, true}, {"AND", (*CPU6502).AND, zpx, 4, false}, {"ROL", (*CPU6502).ROL, zpx, 6, false}, {"RLA", (*CPU6502).RLA, zpx, 6, true}, {"SEC", (*CPU6502).SEC, imp, 2, false}, {"AND", (*CPU6502).AND, aby, 4, false}, {"NOP", (*CPU6502).NOP, imp, 2, true}, {"RLA", (*CPU6502).RLA, aby, 7, true}, {"NOP", (*CPU6502).NOP, abx, 4, true}, {"AND", (*CPU6502).AND, abx, 4, false}, {"ROL", (*CPU6502).ROL, abx, 7, false}, {"RLA", (*CPU6502).RLA, abx, 7, true},
+	{"RTI", (*CPU6502).RTI, imp, 6, false}, {"EOR", (*CPU6502).EOR, izx, 6, false}, {"JAM", (*CPU6502).JAM, imp, 2, true}, {"SRE", (*CPU6502).SRE, izx, 8, true}, {"NOP", (*CPU6502).NOP, zp0, 3, true}, {"EOR", (*CPU6502).EOR, zp0, 3, false}, {"LSR", (*CPU6502).LSR, zp0, 5, false}, {"SRE", (*CPU6502).SRE, zp0, 5, true}, {"PHA", (*CPU6502).PHA, imp, 3, false}, {"EOR", (*CPU6502).EOR, imm, 2, false}, {"LSR", (*CPU6502).LSR, acc, 2, false}, {"ALR", (*CPU6502).ALR, imm, 2, true}, {"JMP", (*CPU6502).JMP, abs, 3, false}, {"EOR", (*CPU6502).EOR, abs, 4, false}, {"LSR", (*CPU6502).LSR, abs, 6, false}, {"SRE", (*CPU6502).SRE, abs, 6, true},
+	{"BVC", (*CPU6502).BVC, rel, 2, false}, {"EOR", (*CPU6502).EOR, izy, 5, false}, {"JAM", (*CPU6502).JAM, imp, 2, true}, {"SRE", (*CPU6502).SRE, izy, 8, true}, {"NOP", (*CPU6502).NOP, zpx, 4, true}, {"EOR", (*CPU6502).EOR, zpx, 4, false}, {"LSR", (*CPU6502).LSR, zpx, 6, false}, {"SRE", (*CPU6502).SRE, zpx, 6, true}, {"CLI", (*CPU6502).CLI, imp, 2, false}, {"EOR", (*CPU6502).EOR, aby, 4, false}, {"NOP", (*CPU6502).NOP, imp, 2, true}, {"SRE", (*CPU6502).SRE, aby, 7, true}, {"NOP", (*CPU6502).NOP, abx, 4, true}, {"EOR", (*CPU6502).EOR, abx, 4, false}, {"LSR", (*CPU6502).LSR, abx, 7, false}, {"SRE", (*CPU6502).SRE, abx, 7, true},
+	{"RTS", (*CPU6502).RTS, imp, 6, false}, {"ADC", (*CPU6502).ADC, izx, 6, false}, {"JAM", (*CPU6502).JAM, imp, 2, true}, {"RRA", (*CPU6502).RRA, izx, 8, true}, {"NOP", (*CPU6502).NOP, zp0, 3, true}, {"ADC", (*CPU6502).ADC, zp0, 3, false}, {"ROR", (*CPU6502).ROR, zp0, 5, false}, {"RRA", (*CPU6502).RRA, zp0, 5, true}, {"PLA", (*CPU6502).PLA, imp, 4, false}, {"ADC", (*CPU6502).ADC, imm, 2, false}, {"ROR", (*CPU6502).ROR, acc, 2, false}, {"ARR", (*CPU6502).ARR, imm, 2, true}, {"JMP", (*CPU6502).JMP, ind, 5, false}, {"ADC", (*CPU6502).ADC, abs, 4, false}, {"ROR", (*CPU6502).ROR, abs, 6, false}, {"RRA", (*CPU6502).RRA, abs, 6, true},
+	{"BVS", (*CPU6502).BVS, rel, 2, false}, {"ADC", (*CPU6502).ADC, izy, 5, false}, {"JAM", (*CPU6502).JAM, imp, 2, true}, {"RRA", (*CPU6502).RRA, izy, 8, true}, {"NOP", (*CPU6502).NOP, zpx, 4, true}, {"ADC", (*CPU6502).ADC, zpx, 4, false}, {"ROR", (*CPU6502).ROR, zpx, 6, false}, {"RRA", (*CPU6502).RRA, zpx, 6, true}, {"SEI", (*CPU6502).SEI, imp, 2, false}, {"ADC", (*CPU6502).ADC, aby, 4, false}, {"NOP", (*CPU6502).NOP, imp, 2, true}, {"RRA", (*CPU6502).RRA, aby, 7, true}, {"NOP", (*CPU6502).NOP, abx, 4, true}, {"ADC", (*CPU6502).ADC, abx, 4, false}, {"ROR", (*CPU6502).ROR, abx, 7, false}, {"RRA", (*CPU6502).RRA, abx, 7, true},
+	{"NOP", (*CPU6502).NOP, imm, 2, true}, {"STA", (*CPU6502).STA, izx, 6, false}, {"NOP", (*CPU6502).NOP, imm, 2, true}, {"SAX", (*CPU6502).SAX, izx, 6, true}, {"STY", (*CPU6502).STY, zp0, 3, false}, {"STA", (*CPU6502).STA, zp0, 3, false}, {"STX", (*CPU6502).STX, zp0, 3, false}, {"SAX", (*CPU6502).SAX, zp0, 3, true}, {"DEY", (*CPU6502).DEY, imp, 2, false}, {"NOP", (*CPU6502).NOP, imm, 2, true}, {"TXA", (*CPU6502).TXA, imp, 2, false}, {"ANE", (*CPU6502).ANE, imm, 2, true}, {"STY", (*CPU6502).STY, abs, 4, false}, {"STA", (*CPU6502).STA, abs, 4, false}, {"STX", (*CPU6502).STX, abs, 4, false}, {"SAX", (*CPU6502).SAX, abs, 4, true},
+	{"BCC", (*CPU6502).BCC, rel, 2, false}, {"STA", (*CPU6502).STA, izy, 6, false}, {"JAM", (*CPU6502).JAM, imp, 2, true}, {"SHA", (*CPU6502).SHA, izy, 6, true}, {"STY", (*CPU6502).STY, zpx, 4, false}, {"STA", (*CPU6502).STA, zpx, 4, false}, {"STX", (*CPU6502).STX, zpy, 4, false}, {"SAX", (*CPU6502).SAX, zpy, 4, true}, {"TYA", (*CPU6502).TYA, imp, 2, false}, {"STA", (*CPU6502).STA, aby, 5, false}, {"TXS", (*CPU6502).TXS, imp, 2, false}, {"TAS", (*CPU6502).TAS, aby, 5, true}, {"SHY", (*CPU6502).SHY, abx, 5, true}, {"STA", (*CPU6502).STA, abx, 5, false}, {"SHX", (*CPU6502).SHX, aby, 5, true}, {"SHA", (*CPU6502).SHA, aby, 5, true},
+	{"LDY", (*CPU6502).LDY, imm, 2, false}, {"LDA", (*CPU6502).LDA, izx, 6, false}, {"LDX", (*CPU6502).LDX, imm, 2, false}, {"LAX", (*CPU6502).LAX, izx, 6, true}, {"LDY", (*CPU6502).LDY, zp0, 3, false}, {"LDA", (*CPU6502).LDA, zp0, 3, false}, {"LDX", (*CPU6502).LDX, zp0, 3, false}, {"LAX", (*CPU6502).LAX, zp0, 3, true}, {"TAY", (*CPU6502).TAY, imp, 2, false}, {"LDA", (*CPU6502).LDA, imm, 2, false}, {"TAX", (*CPU6502).TAX, imp, 2, false}, {"LXA", (*CPU6502).LXA, imm, 2, true}, {"LDY", (*CPU6502).LDY, abs, 4, false}, {"LDA", (*CPU6502).LDA, abs, 4, false}, {"LDX", (*CPU6502).LDX, abs, 4, false}, {"LAX", (*CPU6502).LAX, abs, 4, true},
+	{"BCS", (*CPU6502).BCS, rel, 2, false}, {"LDA", (*CPU6502).LDA, izy, 5, false}, {"JAM", (*CPU6502).JAM, imp, 2, true}, {"LAX", (*CPU6502).LAX, izy, 5, true}, {"LDY", (*CPU6502).LDY, zpx, 4, false}, {"LDA", (*CPU6502).LDA, zpx, 4, false}, {"LDX", (*CPU6502).LDX, zpy, 4, false}, {"LAX", (*CPU6502).LAX, zpy, 4, true}, {"CLV", (*CPU6502).CLV, imp, 2, false}, {"LDA", (*CPU6502).LDA, aby, 4, false}, {"TSX", (*CPU6502).TSX, imp, 2, false}, {"LAS", (*CPU6502).LAS, aby, 4, true}, {"LDY", (*CPU6502).LDY, abx, 4, false}, {"LDA", (*CPU6502).LDA, abx, 4, false}, {"LDX", (*CPU6502).LDX, aby, 4, false}, {"LAX", (*CPU6502).LAX, aby, 4, true},
+	{"CPY", (*CPU6502).CPY, imm, 2, false}, {"CMP", (*CPU6502).CMP, izx, 6, false}, {"NOP", (*CPU6502).NOP, imm, 2, true}, {"DCP", (*CPU6502).DCP, izx, 8, true}, {"CPY", (*CPU6502).CPY, zp0, 3, false}, {"CMP", (*CPU6502).CMP, zp0, 3, false}, {"DEC", (*CPU6502).DEC, zp0, 5, false}, {"DCP", (*CPU6502).DCP, zp0, 5, true}, {"INY", (*CPU6502).INY, imp, 2, false}, {"CMP", (*CPU6502).CMP, imm, 2, false}, {"DEX", (*CPU6502).DEX, imp, 2, false}, {"AXS", (*CPU6502).AXS, imm, 2, true}, {"CPY", (*CPU6502).CPY, abs, 4, false}, {"CMP", (*CPU6502).CMP, abs, 4, false}, {"DEC", (*CPU6502).DEC, abs, 6, false}, {"DCP", (*CPU6502).DCP, abs, 6, true},
+	{"BNE", (*CPU6502).BNE, rel, 2, false}, {"CMP", (*CPU6502).CMP, izy, 5, false}, {"JAM", (*CPU6502).JAM, imp, 2, true}, {"DCP", (*CPU6502).DCP, izy, 8, true}, {"NOP", (*CPU6502).NOP, zpx, 4, true}, {"CMP", (*CPU6502).CMP, zpx, 4, false}, {"DEC", (*CPU6502).DEC, zpx, 6, false}, {"DCP", (*CPU6502).DCP, zpx, 6, true}, {"CLD", (*CPU6502).CLD, imp, 2, false}, {"CMP", (*CPU6502).CMP, aby, 4, false}, {"NOP", (*CPU6502).NOP, imp, 2, true}, {"DCP", (*CPU6502).DCP, aby, 7, true}, {"NOP", (*CPU6502).NOP, abx, 4, true}, {"CMP", (*CPU6502).CMP, abx, 4, false}, {"DEC", (*CPU6502).DEC, abx, 7, false}, {"DCP", (*CPU6502).DCP, abx, 7, true},
+	{"CPX", (*CPU6502).CPX, imm, 2, false}, {"SBC", (*CPU6502).SBC, izx, 6, false}, {"NOP", (*CPU6502).NOP, imm, 2, true}, {"ISC", (*CPU6502).ISC, izx, 8, true}, {"CPX", (*CPU6502).CPX, zp0, 3, false}, {"SBC", (*CPU6502).SBC, zp0, 3, false}, {"INC", (*CPU6502).INC, zp0, 5, false}, {"ISC", (*CPU6502).ISC, zp0, 5, true}, {"INX", (*CPU6502).INX, imp, 2, false}, {"SBC", (*CPU6502).SBC, imm, 2, false}, {"NOP", (*CPU6502).NOP, imp, 2, false}, {"SBC", (*CPU6502).SBC, imm, 2, true}, {"CPX", (*CPU6502).CPX, abs, 4, false}, {"SBC", (*CPU6502).SBC, abs, 4, false}, {"INC", (*CPU6502).INC, abs, 6, false}, {"ISC", (*CPU6502).ISC, abs, 6, true},
+	{"BEQ", (*CPU6502).BEQ, rel, 2, false}, {"SBC", (*CPU6502).SBC, izy, 5, false}, {"JAM", (*CPU6502).JAM, imp, 2, true}, {"ISC", (*CPU6502).ISC, izy, 8, true}, {"NOP", (*CPU6502).NOP, zpx, 4, true}, {"SBC", (*CPU6502).SBC, zpx, 4, false}, {"INC", (*CPU6502).INC, zpx, 6, false}, {"ISC", (*CPU6502).ISC, zpx, 6, true}, {"SED", (*CPU6502).SED, imp, 2, false}, {"SBC", (*CPU6502).SBC, aby, 4, false}, {"NOP", (*CPU6502).NOP, imp, 2, true}, {"ISC", (*CPU6502).ISC, aby, 7, true}, {"NOP", (*CPU6502).NOP, abx, 4, true}, {"SBC", (*CPU6502).SBC, abx, 4, false}, {"INC", (*CPU6502).INC, abx, 7, false}, {"ISC", (*CPU6502).ISC, abx, 7, true},
 }
 
 func NewCPU6502() *CPU6502 {
-	newCPU6502 := &CPU6502{}
-
-	newCPU6502.lookup = []instruction{
-		{"BRK", newCPU6502.BRK, newCPU6502.IMM, 7}, {"ORA", newCPU6502.ORA, newCPU6502.IZX, 6}, {"???", newCPU6502.ERR, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 8}, {"???", newCPU6502.NOP, newCPU6502.IMP, 3}, {"ORA", newCPU6502.ORA, newCPU6502.ZP0, 3}, {"ASL", newCPU6502.ASL, newCPU6502.ZP0, 5}, {"???", newCPU6502.ERR, newCPU6502.IMP, 5}, {"PHP", newCPU6502.PHP, newCPU6502.IMP, 3}, {"ORA", newCPU6502.ORA, newCPU6502.IMM, 2}, {"ASL", newCPU6502.ASL, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 2}, {"???", newCPU6502.NOP, newCPU6502.IMP, 4}, {"ORA", newCPU6502.ORA, newCPU6502.ABS, 4}, {"ASL", newCPU6502.ASL, newCPU6502.ABS, 6}, {"???", newCPU6502.ERR, newCPU6502.IMP, 6},
-		{"BPL", newCPU6502.BPL, newCPU6502.REL, 2}, {"ORA", newCPU6502.ORA, newCPU6502.IZY, 5}, {"???", newCPU6502.ERR, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 8}, {"???", newCPU6502.NOP, newCPU6502.IMP, 4}, {"ORA", newCPU6502.ORA, newCPU6502.ZPX, 4}, {"ASL", newCPU6502.ASL, newCPU6502.ZPX, 6}, {"???", newCPU6502.ERR, newCPU6502.IMP, 6}, {"CLC", newCPU6502.CLC, newCPU6502.IMP, 2}, {"ORA", newCPU6502.ORA, newCPU6502.ABY, 4}, {"???", newCPU6502.NOP, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 7}, {"???", newCPU6502.NOP, newCPU6502.IMP, 4}, {"ORA", newCPU6502.ORA, newCPU6502.ABX, 4}, {"ASL", newCPU6502.ASL, newCPU6502.ABX, 7}, {"???", newCPU6502.ERR, newCPU6502.IMP, 7},
-		{"JSR", newCPU6502.JSR, newCPU6502.ABS, 6}, {"AND", newCPU6502.AND, newCPU6502.IZX, 6}, {"???", newCPU6502.ERR, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 8}, {"BIT", newCPU6502.BIT, newCPU6502.ZP0, 3}, {"AND", newCPU6502.AND, newCPU6502.ZP0, 3}, {"ROL", newCPU6502.ROL, newCPU6502.ZP0, 5}, {"???", newCPU6502.ERR, newCPU6502.IMP, 5}, {"PLP", newCPU6502.PLP, newCPU6502.IMP, 4}, {"AND", newCPU6502.AND, newCPU6502.IMM, 2}, {"ROL", newCPU6502.ROL, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 2}, {"BIT", newCPU6502.BIT, newCPU6502.ABS, 4}, {"AND", newCPU6502.AND, newCPU6502.ABS, 4}, {"ROL", newCPU6502.ROL, newCPU6502.ABS, 6}, {"???", newCPU6502.ERR, newCPU6502.IMP, 6},
-		{"BMI", newCPU6502.BMI, newCPU6502.REL, 2}, {"AND", newCPU6502.AND, newCPU6502.IZY, 5}, {"???", newCPU6502.ERR, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 8}, {"???", newCPU6502.NOP, newCPU6502.IMP, 4}, {"AND", newCPU6502.AND, newCPU6502.ZPX, 4}, {"ROL", newCPU6502.ROL, newCPU6502.ZPX, 6}, {"???", newCPU6502.ERR, newCPU6502.IMP, 6}, {"SEC", newCPU6502.SEC, newCPU6502.IMP, 2}, {"AND", newCPU6502.AND, newCPU6502.ABY, 4}, {"???", newCPU6502.NOP, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 7}, {"???", newCPU6502.NOP, newCPU6502.IMP, 4}, {"AND", newCPU6502.AND, newCPU6502.ABX, 4}, {"ROL", newCPU6502.ROL, newCPU6502.ABX, 7}, {"???", newCPU6502.ERR, newCPU6502.IMP, 7},
-		{"RTI", newCPU6502.RTI, newCPU6502.IMP, 6}, {"EOR", newCPU6502.EOR, newCPU6502.IZX, 6}, {"???", newCPU6502.ERR, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 8}, {"???", newCPU6502.NOP, newCPU6502.IMP, 3}, {"EOR", newCPU6502.EOR, newCPU6502.ZP0, 3}, {"LSR", newCPU6502.LSR, newCPU6502.ZP0, 5}, {"???", newCPU6502.ERR, newCPU6502.IMP, 5}, {"PHA", newCPU6502.PHA, newCPU6502.IMP, 3}, {"EOR", newCPU6502.EOR, newCPU6502.IMM, 2}, {"LSR", newCPU6502.LSR, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 2}, {"JMP", newCPU6502.JMP, newCPU6502.ABS, 3}, {"EOR", newCPU6502.EOR, newCPU6502.ABS, 4}, {"LSR", newCPU6502.LSR, newCPU6502.ABS, 6}, {"???", newCPU6502.ERR, newCPU6502.IMP, 6},
-		{"BVC", newCPU6502.BVC, newCPU6502.REL, 2}, {"EOR", newCPU6502.EOR, newCPU6502.IZY, 5}, {"???", newCPU6502.ERR, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 8}, {"???", newCPU6502.NOP, newCPU6502.IMP, 4}, {"EOR", newCPU6502.EOR, newCPU6502.ZPX, 4}, {"LSR", newCPU6502.LSR, newCPU6502.ZPX, 6}, {"???", newCPU6502.ERR, newCPU6502.IMP, 6}, {"CLI", newCPU6502.CLI, newCPU6502.IMP, 2}, {"EOR", newCPU6502.EOR, newCPU6502.ABY, 4}, {"???", newCPU6502.NOP, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 7}, {"???", newCPU6502.NOP, newCPU6502.IMP, 4}, {"EOR", newCPU6502.EOR, newCPU6502.ABX, 4}, {"LSR", newCPU6502.LSR, newCPU6502.ABX, 7}, {"???", newCPU6502.ERR, newCPU6502.IMP, 7},
-		{"RTS", newCPU6502.RTS, newCPU6502.IMP, 6}, {"ADC", newCPU6502.ADC, newCPU6502.IZX, 6}, {"???", newCPU6502.ERR, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 8}, {"???", newCPU6502.NOP, newCPU6502.IMP, 3}, {"ADC", newCPU6502.ADC, newCPU6502.ZP0, 3}, {"ROR", newCPU6502.ROR, newCPU6502.ZP0, 5}, {"???", newCPU6502.ERR, newCPU6502.IMP, 5}, {"PLA", newCPU6502.PLA, newCPU6502.IMP, 4}, {"ADC", newCPU6502.ADC, newCPU6502.IMM, 2}, {"ROR", newCPU6502.ROR, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 2}, {"JMP", newCPU6502.JMP, newCPU6502.IND, 5}, {"ADC", newCPU6502.ADC, newCPU6502.ABS, 4}, {"ROR", newCPU6502.ROR, newCPU6502.ABS, 6}, {"???", newCPU6502.ERR, newCPU6502.IMP, 6},
-		{"BVS", newCPU6502.BVS, newCPU6502.REL, 2}, {"ADC", newCPU6502.ADC, newCPU6502.IZY, 5}, {"???", newCPU6502.ERR, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 8}, {"???", newCPU6502.NOP, newCPU6502.IMP, 4}, {"ADC", newCPU6502.ADC, newCPU6502.ZPX, 4}, {"ROR", newCPU6502.ROR, newCPU6502.ZPX, 6}, {"???", newCPU6502.ERR, newCPU6502.IMP, 6}, {"SEI", newCPU6502.SEI, newCPU6502.IMP, 2}, {"ADC", newCPU6502.ADC, newCPU6502.ABY, 4}, {"???", newCPU6502.NOP, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 7}, {"???", newCPU6502.NOP, newCPU6502.IMP, 4}, {"ADC", newCPU6502.ADC, newCPU6502.ABX, 4}, {"ROR", newCPU6502.ROR, newCPU6502.ABX, 7}, {"???", newCPU6502.ERR, newCPU6502.IMP, 7},
-		{"???", newCPU6502.NOP, newCPU6502.IMP, 2}, {"STA", newCPU6502.STA, newCPU6502.IZX, 6}, {"???", newCPU6502.NOP, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 6}, {"STY", newCPU6502.STY, newCPU6502.ZP0, 3}, {"STA", newCPU6502.STA, newCPU6502.ZP0, 3}, {"STX", newCPU6502.STX, newCPU6502.ZP0, 3}, {"???", newCPU6502.ERR, newCPU6502.IMP, 3}, {"DEY", newCPU6502.DEY, newCPU6502.IMP, 2}, {"???", newCPU6502.NOP, newCPU6502.IMP, 2}, {"TXA", newCPU6502.TXA, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 2}, {"STY", newCPU6502.STY, newCPU6502.ABS, 4}, {"STA", newCPU6502.STA, newCPU6502.ABS, 4}, {"STX", newCPU6502.STX, newCPU6502.ABS, 4}, {"???", newCPU6502.ERR, newCPU6502.IMP, 4},
-		{"BCC", newCPU6502.BCC, newCPU6502.REL, 2}, {"STA", newCPU6502.STA, newCPU6502.IZY, 6}, {"???", newCPU6502.ERR, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 6}, {"STY", newCPU6502.STY, newCPU6502.ZPX, 4}, {"STA", newCPU6502.STA, newCPU6502.ZPX, 4}, {"STX", newCPU6502.STX, newCPU6502.ZPY, 4}, {"???", newCPU6502.ERR, newCPU6502.IMP, 4}, {"TYA", newCPU6502.TYA, newCPU6502.IMP, 2}, {"STA", newCPU6502.STA, newCPU6502.ABY, 5}, {"TXS", newCPU6502.TXS, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 5}, {"???", newCPU6502.NOP, newCPU6502.IMP, 5}, {"STA", newCPU6502.STA, newCPU6502.ABX, 5}, {"???", newCPU6502.ERR, newCPU6502.IMP, 5}, {"???", newCPU6502.ERR, newCPU6502.IMP, 5},
-		{"LDY", newCPU6502.LDY, newCPU6502.IMM, 2}, {"LDA", newCPU6502.LDA, newCPU6502.IZX, 6}, {"LDX", newCPU6502.LDX, newCPU6502.IMM, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 6}, {"LDY", newCPU6502.LDY, newCPU6502.ZP0, 3}, {"LDA", newCPU6502.LDA, newCPU6502.ZP0, 3}, {"LDX", newCPU6502.LDX, newCPU6502.ZP0, 3}, {"???", newCPU6502.ERR, newCPU6502.IMP, 3}, {"TAY", newCPU6502.TAY, newCPU6502.IMP, 2}, {"LDA", newCPU6502.LDA, newCPU6502.IMM, 2}, {"TAX", newCPU6502.TAX, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 2}, {"LDY", newCPU6502.LDY, newCPU6502.ABS, 4}, {"LDA", newCPU6502.LDA, newCPU6502.ABS, 4}, {"LDX", newCPU6502.LDX, newCPU6502.ABS, 4}, {"???", newCPU6502.ERR, newCPU6502.IMP, 4},
-		{"BCS", newCPU6502.BCS, newCPU6502.REL, 2}, {"LDA", newCPU6502.LDA, newCPU6502.IZY, 5}, {"???", newCPU6502.ERR, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 5}, {"LDY", newCPU6502.LDY, newCPU6502.ZPX, 4}, {"LDA", newCPU6502.LDA, newCPU6502.ZPX, 4}, {"LDX", newCPU6502.LDX, newCPU6502.ZPY, 4}, {"???", newCPU6502.ERR, newCPU6502.IMP, 4}, {"CLV", newCPU6502.CLV, newCPU6502.IMP, 2}, {"LDA", newCPU6502.LDA, newCPU6502.ABY, 4}, {"TSX", newCPU6502.TSX, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 4}, {"LDY", newCPU6502.LDY, newCPU6502.ABX, 4}, {"LDA", newCPU6502.LDA, newCPU6502.ABX, 4}, {"LDX", newCPU6502.LDX, newCPU6502.ABY, 4}, {"???", newCPU6502.ERR, newCPU6502.IMP, 4},
-		{"CPY", newCPU6502.CPY, newCPU6502.IMM, 2}, {"CMP", newCPU6502.CMP, newCPU6502.IZX, 6}, {"???", newCPU6502.NOP, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 8}, {"CPY", newCPU6502.CPY, newCPU6502.ZP0, 3}, {"CMP", newCPU6502.CMP, newCPU6502.ZP0, 3}, {"DEC", newCPU6502.DEC, newCPU6502.ZP0, 5}, {"???", newCPU6502.ERR, newCPU6502.IMP, 5}, {"INY", newCPU6502.INY, newCPU6502.IMP, 2}, {"CMP", newCPU6502.CMP, newCPU6502.IMM, 2}, {"DEX", newCPU6502.DEX, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 2}, {"CPY", newCPU6502.CPY, newCPU6502.ABS, 4}, {"CMP", newCPU6502.CMP, newCPU6502.ABS, 4}, {"DEC", newCPU6502.DEC, newCPU6502.ABS, 6}, {"???", newCPU6502.ERR, newCPU6502.IMP, 6},
-		{"BNE", newCPU6502.BNE, newCPU6502.REL, 2}, {"CMP", newCPU6502.CMP, newCPU6502.IZY, 5}, {"???", newCPU6502.ERR, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 8}, {"???", newCPU6502.NOP, newCPU6502.IMP, 4}, {"CMP", newCPU6502.CMP, newCPU6502.ZPX, 4}, {"DEC", newCPU6502.DEC, newCPU6502.ZPX, 6}, {"???", newCPU6502.ERR, newCPU6502.IMP, 6}, {"CLD", newCPU6502.CLD, newCPU6502.IMP, 2}, {"CMP", newCPU6502.CMP, newCPU6502.ABY, 4}, {"NOP", newCPU6502.NOP, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 7}, {"???", newCPU6502.NOP, newCPU6502.IMP, 4}, {"CMP", newCPU6502.CMP, newCPU6502.ABX, 4}, {"DEC", newCPU6502.DEC, newCPU6502.ABX, 7}, {"???", newCPU6502.ERR, newCPU6502.IMP, 7},
-		{"CPX", newCPU6502.CPX, newCPU6502.IMM, 2}, {"SBC", newCPU6502.SBC, newCPU6502.IZX, 6}, {"???", newCPU6502.NOP, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 8}, {"CPX", newCPU6502.CPX, newCPU6502.ZP0, 3}, {"SBC", newCPU6502.SBC, newCPU6502.ZP0, 3}, {"INC", newCPU6502.INC, newCPU6502.ZP0, 5}, {"???", newCPU6502.ERR, newCPU6502.IMP, 5}, {"INX", newCPU6502.INX, newCPU6502.IMP, 2}, {"SBC", newCPU6502.SBC, newCPU6502.IMM, 2}, {"NOP", newCPU6502.NOP, newCPU6502.IMP, 2}, {"???", newCPU6502.SBC, newCPU6502.IMP, 2}, {"CPX", newCPU6502.CPX, newCPU6502.ABS, 4}, {"SBC", newCPU6502.SBC, newCPU6502.ABS, 4}, {"INC", newCPU6502.INC, newCPU6502.ABS, 6}, {"???", newCPU6502.ERR, newCPU6502.IMP, 6},
-		{"BEQ", newCPU6502.BEQ, newCPU6502.REL, 2}, {"SBC", newCPU6502.SBC, newCPU6502.IZY, 5}, {"???", newCPU6502.ERR, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 8}, {"???", newCPU6502.NOP, newCPU6502.IMP, 4}, {"SBC", newCPU6502.SBC, newCPU6502.ZPX, 4}, {"INC", newCPU6502.INC, newCPU6502.ZPX, 6}, {"???", newCPU6502.ERR, newCPU6502.IMP, 6}, {"SED", newCPU6502.SED, newCPU6502.IMP, 2}, {"SBC", newCPU6502.SBC, newCPU6502.ABY, 4}, {"NOP", newCPU6502.NOP, newCPU6502.IMP, 2}, {"???", newCPU6502.ERR, newCPU6502.IMP, 7}, {"???", newCPU6502.NOP, newCPU6502.IMP, 4}, {"SBC", newCPU6502.SBC, newCPU6502.ABX, 4}, {"INC", newCPU6502.INC, newCPU6502.ABX, 7}, {"???", newCPU6502.ERR, newCPU6502.IMP, 7},
-	}
-
-	return newCPU6502
+	return &CPU6502{}
 }
 
-func (this *CPU6502) ConnectBus(bus *Bus) {
+func (this *CPU6502) ConnectBus(bus CPUBus) {
 	this.bus = bus
 }
 
+// EnableDecimalMode turns on BCD arithmetic for ADC and SBC. The 2A03 inside
+// the NES has it cut out, so it is off by default; generic 6502 programs
+// such as the Klaus Dormann test suites need it.
+func (this *CPU6502) EnableDecimalMode(enabled bool) {
+	this.hasDecimalMode = enabled
+}
+
 func (this *CPU6502) setFlag(flag Flag, value bool) {
 	if value {
 		this.statusReg |= flag
@@ -86,25 +152,88 @@ func (this *CPU6502) getFlag(flag Flag) uint8 {
 }
 
 func (this *CPU6502) ClockSignal() {
-	if this.amountOfClockCycles == 0 {
+	this.applyExecutionMode()
+
+	if this.cycleAccurate {
+		this.clockCount++
+		this.cycleAccurateClockSignal()
+		return
+	}
+
+	this.updateInterruptDetectors()
+
+	if this.amountOfClockCycles == 0 && this.interruptPolledAtBoundary() {
+		this.hardwareInterrupt = true
+		this.interrupt()
+		this.interruptDisableAtPoll = true
+	} else if this.amountOfClockCycles == 0 {
+		this.hardwareInterrupt = false
+		this.interruptDisableAtPoll = this.getFlag(disableInterrupts) == 1
 		this.opCode = this.read(this.programCounterReg, false)
 		this.programCounterReg++
-		this.amountOfClockCycles = this.lookup[this.opCode].requiredAmountOfClockCycles
+		currentInstruction := &lookup[this.opCode]
+		this.amountOfClockCycles = currentInstruction.requiredAmountOfClockCycles
+
+		this.addressingMode = currentInstruction.addressingMode
 
-		additionalCycle1 := (this.lookup[this.opCode].addressingMode)()
-		additionalCycle2 := (this.lookup[this.opCode].operation)()
+		additionalCycle1 := this.resolveAddressingMode(this.addressingMode)
+		additionalCycle2 := currentInstruction.operation(this)
 
 		this.amountOfClockCycles += (additionalCycle1 & additionalCycle2)
+
+		this.takenBranchOnSamePage = operationKinds[this.opCode] == branchKind && this.amountOfClockCycles == 3
+
+		// RTI restores I before the polling point, CLI, SEI and PLP after it.
+		if this.opCode == 0x40 {
+			this.interruptDisableAtPoll = this.getFlag(disableInterrupts) == 1
+		}
 	}
+
+	// The whole instruction has already run, so the lines are sampled
+	// against the I flag it started with.
+	this.interruptPollHistory <<= 1
+	if this.nmiPending || (this.irqAsserted && !this.interruptDisableAtPoll) {
+		this.interruptPollHistory |= 1
+	}
+
+	this.clockCount++
 	this.amountOfClockCycles--
 }
 
+// interruptPolledAtBoundary is the instruction-level counterpart of
+// interruptPolledForInstruction: the lines sampled on the second-to-last
+// cycle of the previous instruction decide, or on the third-to-last after
+// a taken branch that stayed on its page.
+func (this *CPU6502) interruptPolledAtBoundary() bool {
+	if this.hardwareInterrupt {
+		return false
+	}
+
+	if this.takenBranchOnSamePage {
+		return this.interruptPollHistory&0x04 != 0
+	}
+
+	return this.interruptPollHistory&0x02 != 0
+}
+
+// HaltSignal spends one cycle with RDY pulled low, as a DMA does. The CPU
+// does no work but the cycle still counts.
+func (this *CPU6502) HaltSignal() {
+	this.clockCount++
+}
+
+// InstructionComplete reports whether the next ClockSignal will fetch a new
+// opcode, i.e. the CPU sits on an instruction boundary.
+func (this *CPU6502) InstructionComplete() bool {
+	return this.amountOfClockCycles == 0 && this.instructionStep == 0
+}
+
 func (this *CPU6502) ResetSignal() {
 	this.accumulatorReg = 0
 	this.xReg = 0
 	this.yReg = 0
 	this.stackPointerReg = 0xFD
-	this.statusReg = 0x00 | unused
+	this.statusReg = 0x00 | unused | disableInterrupts
 
 	this.absoluteAddress = 0xFFFC
 	lowByte := uint16(this.read(this.absoluteAddress+0, false))
@@ -116,69 +245,241 @@ func (this *CPU6502) ResetSignal() {
 	this.absoluteAddress = 0x0000
 	this.fetchedData = 0x00
 
-	this.amountOfClockCycles = 8
+	this.irqLine = false
+	this.irqAsserted = false
+	this.nmiEdge = false
+	this.nmiPending = false
+	this.interruptRequested = false
+	this.interruptDisableAtPoll = true
+	this.interruptPollHistory = 0
+	this.hardwareInterrupt = false
+	this.takenBranchOnSamePage = false
+	this.instructionStep = 0
+
+	this.amountOfClockCycles = 7
 }
 
+// InterruptRequestSignal pulls the IRQ line low for the current cycle. IRQ
+// is level triggered: a source keeps calling it on every cycle it holds the
+// line, and the CPU services it once the I flag allows at a polling point.
 func (this *CPU6502) InterruptRequestSignal() {
-	if this.getFlag(disableInterrupts) == 0 {
-		this.write(STACK_HARCODED_ADDR+uint16(this.stackPointerReg), uint8((this.programCounterReg>>8)&0x00FF))
-		this.stackPointerReg--
-		this.write(STACK_HARCODED_ADDR+uint16(this.stackPointerReg), uint8(this.programCounterReg)&0x00FF)
-		this.stackPointerReg--
-
-		this.setFlag(break_, false)
-		this.setFlag(unused, true)
-		this.setFlag(disableInterrupts, true)
+	this.irqLine = true
+}
 
-		this.write(STACK_HARCODED_ADDR+uint16(this.stackPointerReg), uint8(this.statusReg))
-		this.stackPointerReg--
+// NonMaskableInterruptRequestSignal reports a falling edge on the NMI line.
+// The edge is remembered until the CPU services it.
+func (this *CPU6502) NonMaskableInterruptRequestSignal() {
+	this.nmiEdge = true
+}
 
-		this.absoluteAddress = 0xFFFE
-		lowByte := this.read(this.absoluteAddress+0, false)
-		highByte := this.read(this.absoluteAddress+1, false)
-		this.programCounterReg = (uint16(highByte) << 8) | uint16(lowByte)
+// updateInterruptDetectors runs at the start of every cycle: whatever was
+// signalled during the previous cycle becomes visible to the polling logic.
+func (this *CPU6502) updateInterruptDetectors() {
+	this.irqAsserted = this.irqLine
+	this.irqLine = false
 
-		this.amountOfClockCycles = 7
+	if this.nmiEdge {
+		this.nmiPending = true
+		this.nmiEdge = false
 	}
 }
 
-func (this *CPU6502) NonMaskableInterruptRequestSignal() {
-	this.write(STACK_HARCODED_ADDR+uint16(this.stackPointerReg), uint8((this.programCounterReg>>8)&0x00FF))
-	this.stackPointerReg--
-	this.write(STACK_HARCODED_ADDR+uint16(this.stackPointerReg), uint8(this.programCounterReg)&0x00FF)
-	this.stackPointerReg--
+func (this *CPU6502) interruptPoll() bool {
+	return this.nmiPending || (this.irqAsserted && this.getFlag(disableInterrupts) == 0)
+}
 
-	this.setFlag(break_, false)
-	this.setFlag(unused, true)
-	this.setFlag(disableInterrupts, true)
+// interruptVector picks the vector at the point BRK and IRQ fetch it, so an
+// NMI that arrived in the meantime hijacks the sequence.
+func (this *CPU6502) interruptVector() uint16 {
+	if this.nmiPending {
+		this.nmiPending = false
+		return 0xFFFA
+	}
+	return 0xFFFE
+}
 
-	this.write(STACK_HARCODED_ADDR+uint16(this.stackPointerReg), uint8(this.statusReg))
-	this.stackPointerReg--
+// interrupt runs the whole IRQ/NMI sequence at once for the
+// instruction-level mode. Unlike BRK it pushes the status with B clear.
+func (this *CPU6502) interrupt() {
+	this.push(uint8((this.programCounterReg >> 8) & 0x00FF))
+	this.push(uint8(this.programCounterReg & 0x00FF))
+	this.push(uint8(this.statusReg&^break_ | unused))
+	this.setFlag(disableInterrupts, true)
 
-	this.absoluteAddress = 0xFFFA
-	lowByte := this.read(this.absoluteAddress+0, false)
-	highByte := this.read(this.absoluteAddress+1, false)
-	this.programCounterReg = (uint16(highByte) << 8) | uint16(lowByte)
+	vector := this.interruptVector()
+	lowByte := uint16(this.read(vector, false))
+	highByte := uint16(this.read(vector+1, false))
+	this.programCounterReg = (highByte << 8) | lowByte
 
-	this.amountOfClockCycles = 8
+	this.amountOfClockCycles = 7
+}
+
+// resolveAddressingMode computes absoluteAddress (or relativeAddress) for the
+// current instruction and returns 1 when indexing crossed a page.
+func (this *CPU6502) resolveAddressingMode(mode addressingMode) uint8 {
+	switch mode {
+	case imm:
+		return this.IMM()
+	case zp0:
+		return this.ZP0()
+	case zpx:
+		return this.ZPX()
+	case zpy:
+		return this.ZPY()
+	case rel:
+		return this.REL()
+	case abs:
+		return this.ABS()
+	case abx:
+		return this.ABX()
+	case aby:
+		return this.ABY()
+	case ind:
+		return this.IND()
+	case izx:
+		return this.IZX()
+	case izy:
+		return this.IZY()
+	default:
+		return this.IMP()
+	}
 }
 
 func (this *CPU6502) fetchData() uint8 {
-	addressingModeIsNotImplied := !(reflect.ValueOf(this.lookup[this.opCode].addressingMode).Pointer() == reflect.ValueOf(this.IMP).Pointer())
-
-	if addressingModeIsNotImplied {
+	if this.addressingMode != imp && this.addressingMode != acc && !this.operandLatched {
 		this.fetchedData = this.read(this.absoluteAddress, false)
 	}
 
 	return this.fetchedData
 }
 
+// writeBack stores the result of a shift or rotate, which targets the
+// accumulator in the accumulator addressing mode.
+func (this *CPU6502) writeBack(data uint8) {
+	if this.addressingMode == acc {
+		this.accumulatorReg = data
+	} else {
+		this.write(this.absoluteAddress, data)
+	}
+}
+
+func (this *CPU6502) push(data uint8) {
+	this.write(STACK_HARCODED_ADDR+uint16(this.stackPointerReg), data)
+	this.stackPointerReg--
+}
+
+func (this *CPU6502) pull() uint8 {
+	this.stackPointerReg++
+	return this.read(STACK_HARCODED_ADDR+uint16(this.stackPointerReg), false)
+}
+
+// branchIf takes a relative branch, costing one extra cycle when taken and
+// another one when the destination lies on a different page.
+func (this *CPU6502) branchIf(condition bool) {
+	this.branchTaken = condition
+
+	if !condition || this.cycleAccurate {
+		return
+	}
+
+	this.amountOfClockCycles++
+	this.absoluteAddress = this.programCounterReg + this.relativeAddress
+
+	needsToCrossAPageBoundary := (this.absoluteAddress & 0xFF00) != (this.programCounterReg & 0xFF00)
+
+	if needsToCrossAPageBoundary {
+		this.amountOfClockCycles++
+	}
+
+	this.programCounterReg = this.absoluteAddress
+}
+
+func (this *CPU6502) addWithCarry(value uint8) {
+	if this.hasDecimalMode && this.getFlag(decimalMode) == 1 {
+		this.addDecimalWithCarry(value)
+		return
+	}
+
+	this.addBinaryWithCarry(value)
+}
+
+func (this *CPU6502) addBinaryWithCarry(value uint8) {
+	temp := uint16(this.accumulatorReg) + uint16(value) + uint16(this.getFlag(carryBit))
+	this.setFlag(carryBit, temp > 255)
+	this.setFlag(zero, (temp&0x00FF) == 0)
+
+	hasOverflowed := (^(uint16(this.accumulatorReg) ^ uint16(value)) & (uint16(this.accumulatorReg) ^ temp) & 0x0080) != 0
+	this.setFlag(overflow, hasOverflowed)
+
+	this.setFlag(negative, temp&0x80 != 0)
+
+	this.accumulatorReg = uint8(temp & 0x00FF)
+}
+
+// addDecimalWithCarry follows the NMOS 6502: Z comes from the binary sum,
+// while N and V are taken before the high nibble is decimal adjusted.
+func (this *CPU6502) addDecimalWithCarry(value uint8) {
+	binarySum := uint16(this.accumulatorReg) + uint16(value) + uint16(this.getFlag(carryBit))
+
+	lowNibble := uint16(this.accumulatorReg&0x0F) + uint16(value&0x0F) + uint16(this.getFlag(carryBit))
+	if lowNibble >= 0x0A {
+		lowNibble = ((lowNibble + 0x06) & 0x0F) + 0x10
+	}
+
+	temp := uint16(this.accumulatorReg&0xF0) + uint16(value&0xF0) + lowNibble
+
+	hasOverflowed := (^(uint16(this.accumulatorReg) ^ uint16(value)) & (uint16(this.accumulatorReg) ^ temp) & 0x0080) != 0
+	this.setFlag(overflow, hasOverflowed)
+	this.setFlag(negative, temp&0x80 != 0)
+	this.setFlag(zero, (binarySum&0x00FF) == 0)
+
+	if temp >= 0xA0 {
+		temp += 0x60
+	}
+
+	this.setFlag(carryBit, temp >= 0x100)
+	this.accumulatorReg = uint8(temp & 0x00FF)
+}
+
+// subtractWithBorrow is an addition of the inverted operand:
+// A + ^M + C == A - M - (1 - C). In decimal mode the NMOS 6502 keeps the
+// binary flags and only adjusts the result.
+func (this *CPU6502) subtractWithBorrow(value uint8) {
+	accumulator := int(this.accumulatorReg)
+	borrow := 1 - int(this.getFlag(carryBit))
+
+	this.addBinaryWithCarry(value ^ 0xFF)
+
+	if !this.hasDecimalMode || this.getFlag(decimalMode) == 0 {
+		return
+	}
+
+	lowNibble := (accumulator & 0x0F) - int(value&0x0F) - borrow
+	if lowNibble < 0 {
+		lowNibble = ((lowNibble - 0x06) & 0x0F) - 0x10
+	}
+
+	temp := (accumulator & 0xF0) - int(value&0xF0) + lowNibble
+	if temp < 0 {
+		temp -= 0x60
+	}
+
+	this.accumulatorReg = uint8(temp & 0xFF)
+}
+
+func (this *CPU6502) compare(register uint8) {
+	temp := uint16(register) - uint16(this.fetchedData)
+	this.setFlag(carryBit, register >= this.fetchedData)
+	this.setFlag(zero, (temp&0x00FF) == 0x0000)
+	this.setFlag(negative, temp&0x0080 != 0)
+}
+
 func (this *CPU6502) write(addr uint16, data uint8) {
 	this.bus.CPUWrite(addr, data)
 }
 
 func (this *CPU6502) read(addr uint16, readOnly bool) uint8 {
-	return this.bus.CPURead(addr, false)
+	return this.bus.CPURead(addr, readOnly)
 }
 
 // Addressing Modes
@@ -188,8 +489,8 @@ func (this *CPU6502) IMP() uint8 {
 }
 
 func (this *CPU6502) IMM() uint8 {
-	this.programCounterReg++
 	this.absoluteAddress = this.programCounterReg
+	this.programCounterReg++
 	return 0
 }
 
@@ -321,23 +622,9 @@ func (this *CPU6502) IZY() uint8 {
 }
 
 // Operations
-func (this *CPU6502) ERR() uint8 { // Non-legitimate OpCode handling
-	return 0
-}
-
 func (this *CPU6502) ADC() uint8 {
 	this.fetchData()
-	temp := uint16(this.accumulatorReg) + uint16(this.fetchedData) + uint16(this.getFlag(carryBit))
-	this.setFlag(carryBit, temp > 255)
-	this.setFlag(zero, (temp&0x00FF) == 0)
-
-	hasOverflowed := (^(uint16(this.accumulatorReg) ^ uint16(this.fetchedData)) & (uint16(this.accumulatorReg) ^ uint16(temp)) & 0x0080) != 0
-	this.setFlag(overflow, hasOverflowed)
-
-	this.setFlag(negative, temp&0x80 != 0)
-
-	this.accumulatorReg = uint8(temp & 0x00FF)
-
+	this.addWithCarry(this.fetchedData)
 	return 1
 }
 
@@ -350,142 +637,78 @@ func (this *CPU6502) AND() uint8 {
 }
 
 func (this *CPU6502) ASL() uint8 {
-	return 0 // not implemented
-}
-
-func (this *CPU6502) BCS() uint8 {
-	if this.getFlag(carryBit) == 1 {
-		this.amountOfClockCycles++
-		this.absoluteAddress = this.programCounterReg + this.relativeAddress
-
-		needsToCrossAPageBoundary := (this.absoluteAddress & 0xFF00) != (this.programCounterReg & 0xFF00)
-
-		if needsToCrossAPageBoundary {
-			this.amountOfClockCycles++
-		}
-
-		this.programCounterReg = this.absoluteAddress
-	}
+	this.fetchData()
+	temp := uint16(this.fetchedData) << 1
+	this.setFlag(carryBit, (temp&0xFF00) > 0)
+	this.setFlag(zero, (temp&0x00FF) == 0x00)
+	this.setFlag(negative, temp&0x80 != 0)
+	this.writeBack(uint8(temp & 0x00FF))
 	return 0
 }
 
 func (this *CPU6502) BCC() uint8 {
-	if this.getFlag(carryBit) == 1 {
-		this.amountOfClockCycles++
-		this.absoluteAddress = this.programCounterReg + this.relativeAddress
-
-		needsToCrossAPageBoundary := (this.absoluteAddress & 0xFF00) != (this.programCounterReg & 0xFF00)
-
-		if needsToCrossAPageBoundary {
-			this.amountOfClockCycles++
-		}
+	this.branchIf(this.getFlag(carryBit) == 0)
+	return 0
+}
 
-		this.programCounterReg = this.absoluteAddress
-	}
+func (this *CPU6502) BCS() uint8 {
+	this.branchIf(this.getFlag(carryBit) == 1)
 	return 0
 }
 
 func (this *CPU6502) BEQ() uint8 {
-	if this.getFlag(carryBit) == 1 {
-		this.amountOfClockCycles++
-		this.absoluteAddress = this.programCounterReg + this.relativeAddress
-
-		needsToCrossAPageBoundary := (this.absoluteAddress & 0xFF00) != (this.programCounterReg & 0xFF00)
-
-		if needsToCrossAPageBoundary {
-			this.amountOfClockCycles++
-		}
-
-		this.programCounterReg = this.absoluteAddress
-	}
+	this.branchIf(this.getFlag(zero) == 1)
 	return 0
 }
 
 func (this *CPU6502) BIT() uint8 {
-	return 0 // not implemented
+	this.fetchData()
+	temp := this.accumulatorReg & this.fetchedData
+	this.setFlag(zero, temp == 0x00)
+	this.setFlag(negative, this.fetchedData&(1<<7) != 0)
+	this.setFlag(overflow, this.fetchedData&(1<<6) != 0)
+	return 0
 }
 
 func (this *CPU6502) BMI() uint8 {
-	if this.getFlag(carryBit) == 1 {
-		this.amountOfClockCycles++
-		this.absoluteAddress = this.programCounterReg + this.relativeAddress
-
-		needsToCrossAPageBoundary := (this.absoluteAddress & 0xFF00) != (this.programCounterReg & 0xFF00)
-
-		if needsToCrossAPageBoundary {
-			this.amountOfClockCycles++
-		}
-
-		this.programCounterReg = this.absoluteAddress
-	}
+	this.branchIf(this.getFlag(negative) == 1)
 	return 0
 }
 
 func (this *CPU6502) BNE() uint8 {
-	if this.getFlag(carryBit) == 1 {
-		this.amountOfClockCycles++
-		this.absoluteAddress = this.programCounterReg + this.relativeAddress
-
-		needsToCrossAPageBoundary := (this.absoluteAddress & 0xFF00) != (this.programCounterReg & 0xFF00)
-
-		if needsToCrossAPageBoundary {
-			this.amountOfClockCycles++
-		}
-
-		this.programCounterReg = this.absoluteAddress
-	}
+	this.branchIf(this.getFlag(zero) == 0)
 	return 0
 }
 
 func (this *CPU6502) BPL() uint8 {
-	if this.getFlag(carryBit) == 1 {
-		this.amountOfClockCycles++
-		this.absoluteAddress = this.programCounterReg + this.relativeAddress
-
-		needsToCrossAPageBoundary := (this.absoluteAddress & 0xFF00) != (this.programCounterReg & 0xFF00)
-
-		if needsToCrossAPageBoundary {
-			this.amountOfClockCycles++
-		}
-
-		this.programCounterReg = this.absoluteAddress
-	}
+	this.branchIf(this.getFlag(negative) == 0)
 	return 0
 }
 
+// BRK is paired with the IMM addressing mode, which has already stepped the
+// program counter over the padding byte, so the return address pushed here is
+// the opcode address + 2.
 func (this *CPU6502) BRK() uint8 {
-	return 0 // not implemented
-}
+	this.push(uint8((this.programCounterReg >> 8) & 0x00FF))
+	this.push(uint8(this.programCounterReg & 0x00FF))
 
-func (this *CPU6502) BVC() uint8 {
-	if this.getFlag(carryBit) == 1 {
-		this.amountOfClockCycles++
-		this.absoluteAddress = this.programCounterReg + this.relativeAddress
-
-		needsToCrossAPageBoundary := (this.absoluteAddress & 0xFF00) != (this.programCounterReg & 0xFF00)
+	this.push(uint8(this.statusReg | break_ | unused))
+	this.setFlag(disableInterrupts, true)
 
-		if needsToCrossAPageBoundary {
-			this.amountOfClockCycles++
-		}
+	vector := this.interruptVector()
+	lowByte := uint16(this.read(vector, false))
+	highByte := uint16(this.read(vector+1, false))
+	this.programCounterReg = (highByte << 8) | lowByte
+	return 0
+}
 
-		this.programCounterReg = this.absoluteAddress
-	}
+func (this *CPU6502) BVC() uint8 {
+	this.branchIf(this.getFlag(overflow) == 0)
 	return 0
 }
 
 func (this *CPU6502) BVS() uint8 {
-	if this.getFlag(carryBit) == 1 {
-		this.amountOfClockCycles++
-		this.absoluteAddress = this.programCounterReg + this.relativeAddress
-
-		needsToCrossAPageBoundary := (this.absoluteAddress & 0xFF00) != (this.programCounterReg & 0xFF00)
-
-		if needsToCrossAPageBoundary {
-			this.amountOfClockCycles++
-		}
-
-		this.programCounterReg = this.absoluteAddress
-	}
+	this.branchIf(this.getFlag(overflow) == 1)
 	return 0
 }
 
@@ -510,185 +733,478 @@ func (this *CPU6502) CLV() uint8 {
 }
 
 func (this *CPU6502) CMP() uint8 {
-	return 0 // not implemented
+	this.fetchData()
+	this.compare(this.accumulatorReg)
+	return 1
 }
 
 func (this *CPU6502) CPX() uint8 {
-	return 0 // not implemented
+	this.fetchData()
+	this.compare(this.xReg)
+	return 0
 }
 
 func (this *CPU6502) CPY() uint8 {
-	return 0 // not implemented
+	this.fetchData()
+	this.compare(this.yReg)
+	return 0
 }
 
 func (this *CPU6502) DEC() uint8 {
-	return 0 // not implemented
+	this.fetchData()
+	temp := this.fetchedData - 1
+	this.write(this.absoluteAddress, temp)
+	this.setFlag(zero, temp == 0x00)
+	this.setFlag(negative, temp&0x80 != 0)
+	return 0
 }
 
 func (this *CPU6502) DEX() uint8 {
-	return 0 // not implemented
+	this.xReg--
+	this.setFlag(zero, this.xReg == 0x00)
+	this.setFlag(negative, this.xReg&0x80 != 0)
+	return 0
 }
 
 func (this *CPU6502) DEY() uint8 {
-	return 0 // not implemented
+	this.yReg--
+	this.setFlag(zero, this.yReg == 0x00)
+	this.setFlag(negative, this.yReg&0x80 != 0)
+	return 0
 }
 
 func (this *CPU6502) EOR() uint8 {
-	return 0 // not implemented
+	this.fetchData()
+	this.accumulatorReg ^= this.fetchedData
+	this.setFlag(zero, this.accumulatorReg == 0x00)
+	this.setFlag(negative, this.accumulatorReg&0x80 != 0)
+	return 1
 }
 
 func (this *CPU6502) INC() uint8 {
-	return 0 // not implemented
+	this.fetchData()
+	temp := this.fetchedData + 1
+	this.write(this.absoluteAddress, temp)
+	this.setFlag(zero, temp == 0x00)
+	this.setFlag(negative, temp&0x80 != 0)
+	return 0
 }
 
 func (this *CPU6502) INX() uint8 {
-	return 0 // not implemented
+	this.xReg++
+	this.setFlag(zero, this.xReg == 0x00)
+	this.setFlag(negative, this.xReg&0x80 != 0)
+	return 0
 }
 
 func (this *CPU6502) INY() uint8 {
-	return 0 // not implemented
+	this.yReg++
+	this.setFlag(zero, this.yReg == 0x00)
+	this.setFlag(negative, this.yReg&0x80 != 0)
+	return 0
 }
 
 func (this *CPU6502) JMP() uint8 {
-	return 0 // not implemented
+	this.programCounterReg = this.absoluteAddress
+	return 0
 }
 
+// JSR pushes the address of its own last operand byte; RTS adds the missing 1.
 func (this *CPU6502) JSR() uint8 {
-	return 0 // not implemented
+	this.programCounterReg--
+	this.push(uint8((this.programCounterReg >> 8) & 0x00FF))
+	this.push(uint8(this.programCounterReg & 0x00FF))
+	this.programCounterReg = this.absoluteAddress
+	return 0
 }
 
 func (this *CPU6502) LDA() uint8 {
-	return 0 // not implemented
+	this.fetchData()
+	this.accumulatorReg = this.fetchedData
+	this.setFlag(zero, this.accumulatorReg == 0x00)
+	this.setFlag(negative, this.accumulatorReg&0x80 != 0)
+	return 1
 }
 
 func (this *CPU6502) LDX() uint8 {
-	return 0 // not implemented
+	this.fetchData()
+	this.xReg = this.fetchedData
+	this.setFlag(zero, this.xReg == 0x00)
+	this.setFlag(negative, this.xReg&0x80 != 0)
+	return 1
 }
 
 func (this *CPU6502) LDY() uint8 {
-	return 0 // not implemented
+	this.fetchData()
+	this.yReg = this.fetchedData
+	this.setFlag(zero, this.yReg == 0x00)
+	this.setFlag(negative, this.yReg&0x80 != 0)
+	return 1
 }
 
 func (this *CPU6502) LSR() uint8 {
-	return 0 // not implemented
+	this.fetchData()
+	this.setFlag(carryBit, this.fetchedData&0x01 != 0)
+	temp := this.fetchedData >> 1
+	this.setFlag(zero, temp == 0x00)
+	this.setFlag(negative, temp&0x80 != 0)
+	this.writeBack(temp)
+	return 0
 }
 
+// NOP still performs the operand read of its addressing mode, and the
+// unofficial absolute,X variants pay the page-crossing penalty like a load.
 func (this *CPU6502) NOP() uint8 {
-	return 0 // not implemented
+	this.fetchData()
+	return 1
 }
 
 func (this *CPU6502) ORA() uint8 {
-	return 0 // not implemented
+	this.fetchData()
+	this.accumulatorReg |= this.fetchedData
+	this.setFlag(zero, this.accumulatorReg == 0x00)
+	this.setFlag(negative, this.accumulatorReg&0x80 != 0)
+	return 1
 }
 
 func (this *CPU6502) PHA() uint8 {
-	this.write(STACK_HARCODED_ADDR+uint16(this.stackPointerReg), this.accumulatorReg)
-	this.stackPointerReg--
+	this.push(this.accumulatorReg)
 	return 0
 }
 
+// PHP always pushes the status with the break and unused bits set.
 func (this *CPU6502) PHP() uint8 {
-	return 0 // not implemented
+	this.push(uint8(this.statusReg | break_ | unused))
+	return 0
 }
 
 func (this *CPU6502) PLA() uint8 {
-	this.stackPointerReg++
-	this.accumulatorReg = this.read(STACK_HARCODED_ADDR+uint16(this.stackPointerReg), false)
+	this.accumulatorReg = this.pull()
 	this.setFlag(zero, this.accumulatorReg == 0x00)
 	this.setFlag(negative, this.accumulatorReg&0x80 != 0)
 	return 0
 }
 
+// PLP ignores the break bit on the stack; it only exists in the pushed copy.
 func (this *CPU6502) PLP() uint8 {
-	return 0 // not implemented
+	this.statusReg = Flag(this.pull())
+	this.statusReg &= ^break_
+	this.statusReg |= unused
+	return 0
 }
 
 func (this *CPU6502) ROL() uint8 {
-	return 0 // not implemented
+	this.fetchData()
+	temp := uint16(this.fetchedData)<<1 | uint16(this.getFlag(carryBit))
+	this.setFlag(carryBit, (temp&0xFF00) > 0)
+	this.setFlag(zero, (temp&0x00FF) == 0x00)
+	this.setFlag(negative, temp&0x80 != 0)
+	this.writeBack(uint8(temp & 0x00FF))
+	return 0
 }
 
 func (this *CPU6502) ROR() uint8 {
-	return 0 // not implemented
+	this.fetchData()
+	temp := uint16(this.getFlag(carryBit))<<7 | uint16(this.fetchedData)>>1
+	this.setFlag(carryBit, this.fetchedData&0x01 != 0)
+	this.setFlag(zero, (temp&0x00FF) == 0x00)
+	this.setFlag(negative, temp&0x80 != 0)
+	this.writeBack(uint8(temp & 0x00FF))
+	return 0
 }
 
 func (this *CPU6502) RTI() uint8 {
-	this.stackPointerReg++
-	this.statusReg = Flag(this.read(STACK_HARCODED_ADDR+uint16(this.stackPointerReg), false))
+	this.statusReg = Flag(this.pull())
 	this.statusReg &= ^break_
-	this.statusReg &= ^unused
+	this.statusReg |= unused
 
-	this.stackPointerReg++
-	this.programCounterReg = uint16(this.read(STACK_HARCODED_ADDR+uint16(this.stackPointerReg), false))
-	this.stackPointerReg++
-	this.programCounterReg |= uint16(this.read(STACK_HARCODED_ADDR+uint16(this.stackPointerReg), false)) << 8
+	this.programCounterReg = uint16(this.pull())
+	this.programCounterReg |= uint16(this.pull()) << 8
 
 	return 0
 }
 
 func (this *CPU6502) RTS() uint8 {
-	return 0 // not implemented
+	this.programCounterReg = uint16(this.pull())
+	this.programCounterReg |= uint16(this.pull()) << 8
+	this.programCounterReg++
+	return 0
 }
 
 func (this *CPU6502) SBC() uint8 {
 	this.fetchData()
-	value := uint16(this.fetchedData) ^ 0x00FF
-	temp := uint16(this.accumulatorReg) + value + uint16(this.getFlag(carryBit))
-	this.setFlag(carryBit, temp > 255)
-	this.setFlag(zero, (temp&0x00FF) == 0)
-
-	hasOverflowed := ((temp ^ uint16(this.accumulatorReg)) & (temp ^ value) & 0x0080) != 0
-	this.setFlag(overflow, hasOverflowed)
-	this.setFlag(negative, temp&0x80 != 0)
-
-	this.accumulatorReg = uint8(temp & 0x00FF)
-
+	this.subtractWithBorrow(this.fetchedData)
 	return 1
 }
 
 func (this *CPU6502) SEC() uint8 {
-	return 0 // not implemented
+	this.setFlag(carryBit, true)
+	return 0
 }
 
 func (this *CPU6502) SED() uint8 {
-	return 0 // not implemented
+	this.setFlag(decimalMode, true)
+	return 0
 }
 
 func (this *CPU6502) SEI() uint8 {
-	return 0 // not implemented
+	this.setFlag(disableInterrupts, true)
+	return 0
 }
 
 func (this *CPU6502) STA() uint8 {
-	return 0 // not implemented
+	this.write(this.absoluteAddress, this.accumulatorReg)
+	return 0
 }
 
 func (this *CPU6502) STX() uint8 {
-	return 0 // not implemented
+	this.write(this.absoluteAddress, this.xReg)
+	return 0
 }
 
 func (this *CPU6502) STY() uint8 {
-	return 0 // not implemented
+	this.write(this.absoluteAddress, this.yReg)
+	return 0
 }
 
 func (this *CPU6502) TAX() uint8 {
-	return 0 // not implemented
+	this.xReg = this.accumulatorReg
+	this.setFlag(zero, this.xReg == 0x00)
+	this.setFlag(negative, this.xReg&0x80 != 0)
+	return 0
 }
 
 func (this *CPU6502) TAY() uint8 {
-	return 0 // not implemented
+	this.yReg = this.accumulatorReg
+	this.setFlag(zero, this.yReg == 0x00)
+	this.setFlag(negative, this.yReg&0x80 != 0)
+	return 0
 }
 
 func (this *CPU6502) TSX() uint8 {
-	return 0 // not implemented
+	this.xReg = this.stackPointerReg
+	this.setFlag(zero, this.xReg == 0x00)
+	this.setFlag(negative, this.xReg&0x80 != 0)
+	return 0
 }
 
 func (this *CPU6502) TXA() uint8 {
-	return 0 // not implemented
+	this.accumulatorReg = this.xReg
+	this.setFlag(zero, this.accumulatorReg == 0x00)
+	this.setFlag(negative, this.accumulatorReg&0x80 != 0)
+	return 0
 }
 
 func (this *CPU6502) TXS() uint8 {
-	return 0 // not implemented
+	this.stackPointerReg = this.xReg
+	return 0
 }
 
 func (this *CPU6502) TYA() uint8 {
-	return 0 // not implemented
+	this.accumulatorReg = this.yReg
+	this.setFlag(zero, this.accumulatorReg == 0x00)
+	this.setFlag(negative, this.accumulatorReg&0x80 != 0)
+	return 0
+}
+
+// Unofficial operations
+//
+// The read-modify-write combinations below (SLO, RLA, SRE, RRA, DCP, ISC)
+// write the modified value back and then feed it to the second operation.
+// Their indexed forms always take the fixed cycle count of the table.
+
+// JAM locks the processor up: the program counter stays on the opcode, so the
+// same instruction is fetched again forever until the next reset.
+func (this *CPU6502) JAM() uint8 {
+	this.programCounterReg--
+	return 0
+}
+
+func (this *CPU6502) SLO() uint8 {
+	this.fetchData()
+	this.setFlag(carryBit, this.fetchedData&0x80 != 0)
+	this.fetchedData <<= 1
+	this.write(this.absoluteAddress, this.fetchedData)
+
+	this.accumulatorReg |= this.fetchedData
+	this.setFlag(zero, this.accumulatorReg == 0x00)
+	this.setFlag(negative, this.accumulatorReg&0x80 != 0)
+	return 0
+}
+
+func (this *CPU6502) RLA() uint8 {
+	this.fetchData()
+	temp := this.fetchedData<<1 | this.getFlag(carryBit)
+	this.setFlag(carryBit, this.fetchedData&0x80 != 0)
+	this.fetchedData = temp
+	this.write(this.absoluteAddress, this.fetchedData)
+
+	this.accumulatorReg &= this.fetchedData
+	this.setFlag(zero, this.accumulatorReg == 0x00)
+	this.setFlag(negative, this.accumulatorReg&0x80 != 0)
+	return 0
+}
+
+func (this *CPU6502) SRE() uint8 {
+	this.fetchData()
+	this.setFlag(carryBit, this.fetchedData&0x01 != 0)
+	this.fetchedData >>= 1
+	this.write(this.absoluteAddress, this.fetchedData)
+
+	this.accumulatorReg ^= this.fetchedData
+	this.setFlag(zero, this.accumulatorReg == 0x00)
+	this.setFlag(negative, this.accumulatorReg&0x80 != 0)
+	return 0
+}
+
+func (this *CPU6502) RRA() uint8 {
+	this.fetchData()
+	temp := this.getFlag(carryBit)<<7 | this.fetchedData>>1
+	this.setFlag(carryBit, this.fetchedData&0x01 != 0)
+	this.fetchedData = temp
+	this.write(this.absoluteAddress, this.fetchedData)
+
+	this.addWithCarry(this.fetchedData)
+	return 0
+}
+
+func (this *CPU6502) DCP() uint8 {
+	this.fetchData()
+	this.fetchedData--
+	this.write(this.absoluteAddress, this.fetchedData)
+
+	this.compare(this.accumulatorReg)
+	return 0
+}
+
+func (this *CPU6502) ISC() uint8 {
+	this.fetchData()
+	this.fetchedData++
+	this.write(this.absoluteAddress, this.fetchedData)
+
+	this.subtractWithBorrow(this.fetchedData)
+	return 0
+}
+
+func (this *CPU6502) LAX() uint8 {
+	this.fetchData()
+	this.accumulatorReg = this.fetchedData
+	this.xReg = this.fetchedData
+	this.setFlag(zero, this.accumulatorReg == 0x00)
+	this.setFlag(negative, this.accumulatorReg&0x80 != 0)
+	return 1
+}
+
+func (this *CPU6502) SAX() uint8 {
+	this.write(this.absoluteAddress, this.accumulatorReg&this.xReg)
+	return 0
+}
+
+func (this *CPU6502) ANC() uint8 {
+	this.fetchData()
+	this.accumulatorReg &= this.fetchedData
+	this.setFlag(zero, this.accumulatorReg == 0x00)
+	this.setFlag(negative, this.accumulatorReg&0x80 != 0)
+	this.setFlag(carryBit, this.accumulatorReg&0x80 != 0)
+	return 0
+}
+
+func (this *CPU6502) ALR() uint8 {
+	this.fetchData()
+	this.accumulatorReg &= this.fetchedData
+	this.setFlag(carryBit, this.accumulatorReg&0x01 != 0)
+	this.accumulatorReg >>= 1
+	this.setFlag(zero, this.accumulatorReg == 0x00)
+	this.setFlag(negative, this.accumulatorReg&0x80 != 0)
+	return 0
+}
+
+// ARR rotates A AND #imm right, but takes carry and overflow from bits 6 and
+// 5 of the result as if it had gone through the adder.
+func (this *CPU6502) ARR() uint8 {
+	this.fetchData()
+	this.accumulatorReg = this.getFlag(carryBit)<<7 | (this.accumulatorReg&this.fetchedData)>>1
+	this.setFlag(zero, this.accumulatorReg == 0x00)
+	this.setFlag(negative, this.accumulatorReg&0x80 != 0)
+	this.setFlag(carryBit, this.accumulatorReg&0x40 != 0)
+	this.setFlag(overflow, ((this.accumulatorReg>>6)^(this.accumulatorReg>>5))&0x01 != 0)
+	return 0
+}
+
+// AXS sets X to (A AND X) - #imm, updating the flags like CMP does.
+func (this *CPU6502) AXS() uint8 {
+	this.fetchData()
+	temp := this.accumulatorReg & this.xReg
+	this.setFlag(carryBit, temp >= this.fetchedData)
+	this.xReg = temp - this.fetchedData
+	this.setFlag(zero, this.xReg == 0x00)
+	this.setFlag(negative, this.xReg&0x80 != 0)
+	return 0
+}
+
+func (this *CPU6502) LAS() uint8 {
+	this.fetchData()
+	this.stackPointerReg &= this.fetchedData
+	this.accumulatorReg = this.stackPointerReg
+	this.xReg = this.stackPointerReg
+	this.setFlag(zero, this.accumulatorReg == 0x00)
+	this.setFlag(negative, this.accumulatorReg&0x80 != 0)
+	return 1
+}
+
+// ANE and LXA depend on analog effects of the chip; the constant ORed into
+// the accumulator is the value most 2A03s settle on.
+func (this *CPU6502) ANE() uint8 {
+	this.fetchData()
+	this.accumulatorReg = (this.accumulatorReg | 0xEE) & this.xReg & this.fetchedData
+	this.setFlag(zero, this.accumulatorReg == 0x00)
+	this.setFlag(negative, this.accumulatorReg&0x80 != 0)
+	return 0
+}
+
+func (this *CPU6502) LXA() uint8 {
+	this.fetchData()
+	this.accumulatorReg = (this.accumulatorReg | 0xFF) & this.fetchedData
+	this.xReg = this.accumulatorReg
+	this.setFlag(zero, this.accumulatorReg == 0x00)
+	this.setFlag(negative, this.accumulatorReg&0x80 != 0)
+	return 0
+}
+
+func (this *CPU6502) SHA() uint8 {
+	this.storeAndHighByte(this.accumulatorReg&this.xReg, this.yReg)
+	return 0
+}
+
+func (this *CPU6502) SHX() uint8 {
+	this.storeAndHighByte(this.xReg, this.yReg)
+	return 0
+}
+
+func (this *CPU6502) SHY() uint8 {
+	this.storeAndHighByte(this.yReg, this.xReg)
+	return 0
+}
+
+func (this *CPU6502) TAS() uint8 {
+	this.stackPointerReg = this.accumulatorReg & this.xReg
+	this.storeAndHighByte(this.stackPointerReg, this.yReg)
+	return 0
+}
+
+// storeAndHighByte implements the SHA/SHX/SHY/TAS store: the value is ANDed
+// with the high byte of the unindexed address plus one, and when indexing
+// crossed a page that same value replaces the high byte of the target.
+func (this *CPU6502) storeAndHighByte(value uint8, index uint8) {
+	baseAddress := this.absoluteAddress - uint16(index)
+	highByte := uint8(baseAddress >> 8)
+	data := value & (highByte + 1)
+
+	needsToCrossAPageBoundary := (baseAddress & 0xFF00) != (this.absoluteAddress & 0xFF00)
+
+	if needsToCrossAPageBoundary {
+		this.absoluteAddress = uint16(data)<<8 | (this.absoluteAddress & 0x00FF)
+	}
+
+	this.write(this.absoluteAddress, data)
 }
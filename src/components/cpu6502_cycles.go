@@ -0,0 +1,438 @@
+package components
+
+// Cycle-accurate execution
+//
+// In this mode every ClockSignal performs the single bus access the 2A03
+// performs on that cycle, including the dummy reads of implied instructions,
+// of indexed addressing before the high byte is fixed and of the stack, and
+// the dummy write of the unmodified value in read-modify-write instructions.
+// Interrupts are polled on the cycle the hardware polls them.
+// The operations themselves are shared with the instruction-level mode: they
+// run on the last cycle, where their single read or write is the real one.
+
+type operationKind uint8
+
+const (
+	readKind operationKind = iota
+	writeKind
+	readModifyWriteKind
+	impliedKind
+	immediateKind
+	branchKind
+	stackKind
+)
+
+var operationKinds [256]operationKind
+
+func init() {
+	for opCode, currentInstruction := range lookup {
+		operationKinds[opCode] = classifyOperation(currentInstruction)
+	}
+}
+
+func classifyOperation(currentInstruction instruction) operationKind {
+	switch currentInstruction.name {
+	case "BRK", "RTI", "RTS", "JSR", "PHA", "PHP", "PLA", "PLP", "JMP":
+		return stackKind
+	case "BPL", "BMI", "BVC", "BVS", "BCC", "BCS", "BNE", "BEQ":
+		return branchKind
+	}
+
+	switch currentInstruction.addressingMode {
+	case imp, acc:
+		return impliedKind
+	case imm:
+		return immediateKind
+	}
+
+	switch currentInstruction.name {
+	case "STA", "STX", "STY", "SAX", "SHA", "SHX", "SHY", "TAS":
+		return writeKind
+	case "ASL", "LSR", "ROL", "ROR", "INC", "DEC", "SLO", "SRE", "RLA", "RRA", "ISC", "DCP":
+		return readModifyWriteKind
+	}
+
+	return readKind
+}
+
+// addressingSteps is the number of cycles, after the opcode fetch, spent
+// before absoluteAddress is known (possibly still needing a page fix-up).
+func (this addressingMode) addressingSteps() uint8 {
+	switch this {
+	case zp0:
+		return 1
+	case zpx, zpy, abs, abx, aby:
+		return 2
+	case izy:
+		return 3
+	case izx:
+		return 4
+	default:
+		return 0
+	}
+}
+
+func (this addressingMode) isIndexedWithFixup() bool {
+	return this == abx || this == aby || this == izy
+}
+
+// EnableCycleAccurateExecution switches between executing a whole
+// instruction on its first cycle (the default, and the fastest) and
+// performing exactly one bus access per ClockSignal. It only takes effect on
+// an instruction boundary: an instruction already under way finishes in the
+// mode it started in.
+func (this *CPU6502) EnableCycleAccurateExecution(enabled bool) {
+	this.nextCycleAccurate = enabled
+	this.applyExecutionMode()
+}
+
+func (this *CPU6502) applyExecutionMode() {
+	if this.cycleAccurate != this.nextCycleAccurate && this.InstructionComplete() {
+		this.cycleAccurate = this.nextCycleAccurate
+	}
+}
+
+func (this *CPU6502) cycleAccurateClockSignal() {
+	this.updateInterruptDetectors()
+
+	// Reset still runs as a block of idle cycles.
+	if this.amountOfClockCycles > 0 {
+		this.amountOfClockCycles--
+		this.recordInterruptPoll()
+		return
+	}
+
+	if this.instructionStep == 0 {
+		// A pending interrupt replaces the fetched opcode with BRK and keeps
+		// the program counter where it is.
+		this.hardwareInterrupt = this.interruptRequested
+		this.interruptRequested = false
+
+		this.opCode = this.read(this.programCounterReg, false)
+		if this.hardwareInterrupt {
+			this.opCode = 0x00
+		} else {
+			this.programCounterReg++
+		}
+
+		this.addressingMode = lookup[this.opCode].addressingMode
+		this.instructionStep = 1
+		this.recordInterruptPoll()
+		return
+	}
+
+	var isDone bool
+
+	switch operationKinds[this.opCode] {
+	case impliedKind:
+		isDone = this.impliedStep()
+	case immediateKind:
+		isDone = this.immediateStep()
+	case branchKind:
+		isDone = this.branchStep()
+	case stackKind:
+		isDone = this.stackStep()
+	default:
+		isDone = this.memoryStep()
+	}
+
+	this.recordInterruptPoll()
+
+	if isDone {
+		this.interruptRequested = this.interruptPolledForInstruction()
+		this.instructionStep = 0
+	} else {
+		this.instructionStep++
+	}
+}
+
+// recordInterruptPoll samples the interrupt lines at the end of a cycle.
+// Bit 0 of the history is the current cycle, bit 1 the previous one.
+func (this *CPU6502) recordInterruptPoll() {
+	this.interruptPollHistory <<= 1
+	if this.interruptPoll() {
+		this.interruptPollHistory |= 1
+	}
+}
+
+// interruptPolledForInstruction decides, on the last cycle of an
+// instruction, whether an interrupt sequence follows. The 6502 polls at the
+// end of the second-to-last cycle, so an I flag changed by the last cycle
+// (CLI, SEI, PLP) only counts after the next instruction. A taken branch
+// that stays on its page does not poll on its extra cycle either, and the
+// interrupt sequence itself never polls.
+func (this *CPU6502) interruptPolledForInstruction() bool {
+	if this.hardwareInterrupt {
+		return false
+	}
+
+	isTakenBranchWithoutPageCross := operationKinds[this.opCode] == branchKind && this.instructionStep == 2
+	if isTakenBranchWithoutPageCross {
+		return this.interruptPollHistory&0x04 != 0
+	}
+
+	return this.interruptPollHistory&0x02 != 0
+}
+
+func (this *CPU6502) impliedStep() bool {
+	this.read(this.programCounterReg, false)
+	this.IMP()
+	lookup[this.opCode].operation(this)
+	return true
+}
+
+func (this *CPU6502) immediateStep() bool {
+	this.IMM()
+	lookup[this.opCode].operation(this)
+	return true
+}
+
+func (this *CPU6502) branchStep() bool {
+	switch this.instructionStep {
+	case 1:
+		this.REL()
+		lookup[this.opCode].operation(this)
+		return !this.branchTaken
+	case 2:
+		this.read(this.programCounterReg, false)
+		this.absoluteAddress = this.programCounterReg + this.relativeAddress
+		this.programCounterReg = (this.programCounterReg & 0xFF00) | (this.absoluteAddress & 0x00FF)
+		return this.programCounterReg == this.absoluteAddress
+	default:
+		this.read(this.programCounterReg, false)
+		this.programCounterReg = this.absoluteAddress
+		return true
+	}
+}
+
+// addressingStep performs one cycle of effective address calculation.
+func (this *CPU6502) addressingStep(step uint8) {
+	switch this.addressingMode {
+	case zp0:
+		this.absoluteAddress = uint16(this.read(this.programCounterReg, false))
+		this.programCounterReg++
+	case zpx, zpy:
+		if step == 1 {
+			this.absoluteAddress = uint16(this.read(this.programCounterReg, false))
+			this.programCounterReg++
+			return
+		}
+
+		this.read(this.absoluteAddress, false)
+		index := this.xReg
+		if this.addressingMode == zpy {
+			index = this.yReg
+		}
+		this.absoluteAddress = (this.absoluteAddress + uint16(index)) & 0x00FF
+	case abs, abx, aby:
+		if step == 1 {
+			this.absoluteAddress = uint16(this.read(this.programCounterReg, false))
+			this.programCounterReg++
+			return
+		}
+
+		this.absoluteAddress |= uint16(this.read(this.programCounterReg, false)) << 8
+		this.programCounterReg++
+
+		if this.addressingMode == abx {
+			this.indexAbsoluteAddress(this.xReg)
+		} else if this.addressingMode == aby {
+			this.indexAbsoluteAddress(this.yReg)
+		}
+	case izx:
+		switch step {
+		case 1:
+			this.pointerAddress = uint16(this.read(this.programCounterReg, false))
+			this.programCounterReg++
+		case 2:
+			this.read(this.pointerAddress, false)
+			this.pointerAddress = (this.pointerAddress + uint16(this.xReg)) & 0x00FF
+		case 3:
+			this.absoluteAddress = uint16(this.read(this.pointerAddress, false))
+		default:
+			this.absoluteAddress |= uint16(this.read((this.pointerAddress+1)&0x00FF, false)) << 8
+		}
+	case izy:
+		switch step {
+		case 1:
+			this.pointerAddress = uint16(this.read(this.programCounterReg, false))
+			this.programCounterReg++
+		case 2:
+			this.absoluteAddress = uint16(this.read(this.pointerAddress, false))
+		default:
+			this.absoluteAddress |= uint16(this.read((this.pointerAddress+1)&0x00FF, false)) << 8
+			this.indexAbsoluteAddress(this.yReg)
+		}
+	}
+}
+
+// indexAbsoluteAddress adds the index register and remembers the address the
+// CPU actually drives first, before it carries into the high byte.
+func (this *CPU6502) indexAbsoluteAddress(index uint8) {
+	baseAddress := this.absoluteAddress
+	this.absoluteAddress += uint16(index)
+	this.uncorrectedAddress = (baseAddress & 0xFF00) | (this.absoluteAddress & 0x00FF)
+	this.pageCrossed = this.uncorrectedAddress != this.absoluteAddress
+}
+
+func (this *CPU6502) memoryStep() bool {
+	addressingSteps := this.addressingMode.addressingSteps()
+
+	if this.instructionStep <= addressingSteps {
+		this.addressingStep(this.instructionStep)
+		return false
+	}
+
+	operandStep := this.instructionStep - addressingSteps
+	kind := operationKinds[this.opCode]
+
+	if this.addressingMode.isIndexedWithFixup() {
+		if operandStep == 1 && (this.pageCrossed || kind != readKind) {
+			this.read(this.uncorrectedAddress, false)
+			return false
+		}
+		if this.pageCrossed || kind != readKind {
+			operandStep--
+		}
+	}
+
+	if kind != readModifyWriteKind {
+		lookup[this.opCode].operation(this)
+		return true
+	}
+
+	switch operandStep {
+	case 1:
+		this.fetchedData = this.read(this.absoluteAddress, false)
+		this.operandLatched = true
+		return false
+	case 2:
+		this.write(this.absoluteAddress, this.fetchedData)
+		return false
+	default:
+		lookup[this.opCode].operation(this)
+		this.operandLatched = false
+		return true
+	}
+}
+
+func (this *CPU6502) stackStep() bool {
+	switch this.opCode {
+	case 0x00: // BRK, and the IRQ/NMI sequence
+		switch this.instructionStep {
+		case 1:
+			this.read(this.programCounterReg, false)
+			if !this.hardwareInterrupt {
+				this.programCounterReg++
+			}
+		case 2:
+			this.push(uint8(this.programCounterReg >> 8))
+		case 3:
+			this.push(uint8(this.programCounterReg & 0x00FF))
+		case 4:
+			if this.hardwareInterrupt {
+				this.push(uint8(this.statusReg&^break_ | unused))
+			} else {
+				this.push(uint8(this.statusReg | break_ | unused))
+			}
+			this.pointerAddress = this.interruptVector()
+		case 5:
+			this.programCounterReg = uint16(this.read(this.pointerAddress, false))
+			this.setFlag(disableInterrupts, true)
+		default:
+			this.programCounterReg |= uint16(this.read(this.pointerAddress+1, false)) << 8
+			return true
+		}
+	case 0x20: // JSR
+		switch this.instructionStep {
+		case 1:
+			this.absoluteAddress = uint16(this.read(this.programCounterReg, false))
+			this.programCounterReg++
+		case 2:
+			this.read(STACK_HARCODED_ADDR+uint16(this.stackPointerReg), false)
+		case 3:
+			this.push(uint8(this.programCounterReg >> 8))
+		case 4:
+			this.push(uint8(this.programCounterReg & 0x00FF))
+		default:
+			this.absoluteAddress |= uint16(this.read(this.programCounterReg, false)) << 8
+			this.programCounterReg = this.absoluteAddress
+			return true
+		}
+	case 0x40: // RTI
+		switch this.instructionStep {
+		case 1:
+			this.read(this.programCounterReg, false)
+		case 2:
+			this.read(STACK_HARCODED_ADDR+uint16(this.stackPointerReg), false)
+		case 3:
+			this.statusReg = Flag(this.pull())
+			this.statusReg &= ^break_
+			this.statusReg |= unused
+		case 4:
+			this.programCounterReg = uint16(this.pull())
+		default:
+			this.programCounterReg |= uint16(this.pull()) << 8
+			return true
+		}
+	case 0x60: // RTS
+		switch this.instructionStep {
+		case 1:
+			this.read(this.programCounterReg, false)
+		case 2:
+			this.read(STACK_HARCODED_ADDR+uint16(this.stackPointerReg), false)
+		case 3:
+			this.programCounterReg = uint16(this.pull())
+		case 4:
+			this.programCounterReg |= uint16(this.pull()) << 8
+		default:
+			this.read(this.programCounterReg, false)
+			this.programCounterReg++
+			return true
+		}
+	case 0x08, 0x48: // PHP, PHA
+		if this.instructionStep == 1 {
+			this.read(this.programCounterReg, false)
+			return false
+		}
+		lookup[this.opCode].operation(this)
+		return true
+	case 0x28, 0x68: // PLP, PLA
+		switch this.instructionStep {
+		case 1:
+			this.read(this.programCounterReg, false)
+		case 2:
+			this.read(STACK_HARCODED_ADDR+uint16(this.stackPointerReg), false)
+		default:
+			lookup[this.opCode].operation(this)
+			return true
+		}
+	case 0x4C: // JMP absolute
+		if this.instructionStep == 1 {
+			this.absoluteAddress = uint16(this.read(this.programCounterReg, false))
+			this.programCounterReg++
+			return false
+		}
+		this.absoluteAddress |= uint16(this.read(this.programCounterReg, false)) << 8
+		this.programCounterReg = this.absoluteAddress
+		return true
+	case 0x6C: // JMP indirect
+		switch this.instructionStep {
+		case 1:
+			this.pointerAddress = uint16(this.read(this.programCounterReg, false))
+			this.programCounterReg++
+		case 2:
+			this.pointerAddress |= uint16(this.read(this.programCounterReg, false)) << 8
+			this.programCounterReg++
+		case 3:
+			this.absoluteAddress = uint16(this.read(this.pointerAddress, false))
+		default:
+			// The high byte is fetched without carrying into the pointer's page.
+			this.absoluteAddress |= uint16(this.read((this.pointerAddress&0xFF00)|((this.pointerAddress+1)&0x00FF), false)) << 8
+			this.programCounterReg = this.absoluteAddress
+			return true
+		}
+	}
+
+	return false
+}
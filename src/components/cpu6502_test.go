@@ -0,0 +1,612 @@
+package components
+
+import (
+	"testing"
+	"time"
+)
+
+type opcodeSpec struct {
+	opCode           uint8
+	name             string
+	addressingMode   addressingMode
+	length           uint16
+	cycles           uint8
+	pageCrossPenalty bool
+}
+
+// officialOpcodes is the 6502 datasheet, written out independently of
+// lookup so the two can be checked against each other.
+var officialOpcodes = []opcodeSpec{
+	{0x69, "ADC", imm, 2, 2, false}, {0x65, "ADC", zp0, 2, 3, false}, {0x75, "ADC", zpx, 2, 4, false}, {0x6D, "ADC", abs, 3, 4, false},
+	{0x7D, "ADC", abx, 3, 4, true}, {0x79, "ADC", aby, 3, 4, true}, {0x61, "ADC", izx, 2, 6, false}, {0x71, "ADC", izy, 2, 5, true},
+	{0x29, "AND", imm, 2, 2, false}, {0x25, "AND", zp0, 2, 3, false}, {0x35, "AND", zpx, 2, 4, false}, {0x2D, "AND", abs, 3, 4, false},
+	{0x3D, "AND", abx, 3, 4, true}, {0x39, "AND", aby, 3, 4, true}, {0x21, "AND", izx, 2, 6, false}, {0x31, "AND", izy, 2, 5, true},
+	{0x0A, "ASL", acc, 1, 2, false}, {0x06, "ASL", zp0, 2, 5, false}, {0x16, "ASL", zpx, 2, 6, false}, {0x0E, "ASL", abs, 3, 6, false}, {0x1E, "ASL", abx, 3, 7, false},
+	{0x90, "BCC", rel, 2, 2, false}, {0xB0, "BCS", rel, 2, 2, false}, {0xF0, "BEQ", rel, 2, 2, false}, {0x30, "BMI", rel, 2, 2, false},
+	{0xD0, "BNE", rel, 2, 2, false}, {0x10, "BPL", rel, 2, 2, false}, {0x50, "BVC", rel, 2, 2, false}, {0x70, "BVS", rel, 2, 2, false},
+	{0x24, "BIT", zp0, 2, 3, false}, {0x2C, "BIT", abs, 3, 4, false},
+	{0x00, "BRK", imm, 2, 7, false},
+	{0x18, "CLC", imp, 1, 2, false}, {0xD8, "CLD", imp, 1, 2, false}, {0x58, "CLI", imp, 1, 2, false}, {0xB8, "CLV", imp, 1, 2, false},
+	{0xC9, "CMP", imm, 2, 2, false}, {0xC5, "CMP", zp0, 2, 3, false}, {0xD5, "CMP", zpx, 2, 4, false}, {0xCD, "CMP", abs, 3, 4, false},
+	{0xDD, "CMP", abx, 3, 4, true}, {0xD9, "CMP", aby, 3, 4, true}, {0xC1, "CMP", izx, 2, 6, false}, {0xD1, "CMP", izy, 2, 5, true},
+	{0xE0, "CPX", imm, 2, 2, false}, {0xE4, "CPX", zp0, 2, 3, false}, {0xEC, "CPX", abs, 3, 4, false},
+	{0xC0, "CPY", imm, 2, 2, false}, {0xC4, "CPY", zp0, 2, 3, false}, {0xCC, "CPY", abs, 3, 4, false},
+	{0xC6, "DEC", zp0, 2, 5, false}, {0xD6, "DEC", zpx, 2, 6, false}, {0xCE, "DEC", abs, 3, 6, false}, {0xDE, "DEC", abx, 3, 7, false},
+	{0xCA, "DEX", imp, 1, 2, false}, {0x88, "DEY", imp, 1, 2, false},
+	{0x49, "EOR", imm, 2, 2, false}, {0x45, "EOR", zp0, 2, 3, false}, {0x55, "EOR", zpx, 2, 4, false}, {0x4D, "EOR", abs, 3, 4, false},
+	{0x5D, "EOR", abx, 3, 4, true}, {0x59, "EOR", aby, 3, 4, true}, {0x41, "EOR", izx, 2, 6, false}, {0x51, "EOR", izy, 2, 5, true},
+	{0xE6, "INC", zp0, 2, 5, false}, {0xF6, "INC", zpx, 2, 6, false}, {0xEE, "INC", abs, 3, 6, false}, {0xFE, "INC", abx, 3, 7, false},
+	{0xE8, "INX", imp, 1, 2, false}, {0xC8, "INY", imp, 1, 2, false},
+	{0x4C, "JMP", abs, 3, 3, false}, {0x6C, "JMP", ind, 3, 5, false},
+	{0x20, "JSR", abs, 3, 6, false},
+	{0xA9, "LDA", imm, 2, 2, false}, {0xA5, "LDA", zp0, 2, 3, false}, {0xB5, "LDA", zpx, 2, 4, false}, {0xAD, "LDA", abs, 3, 4, false},
+	{0xBD, "LDA", abx, 3, 4, true}, {0xB9, "LDA", aby, 3, 4, true}, {0xA1, "LDA", izx, 2, 6, false}, {0xB1, "LDA", izy, 2, 5, true},
+	{0xA2, "LDX", imm, 2, 2, false}, {0xA6, "LDX", zp0, 2, 3, false}, {0xB6, "LDX", zpy, 2, 4, false}, {0xAE, "LDX", abs, 3, 4, false}, {0xBE, "LDX", aby, 3, 4, true},
+	{0xA0, "LDY", imm, 2, 2, false}, {0xA4, "LDY", zp0, 2, 3, false}, {0xB4, "LDY", zpx, 2, 4, false}, {0xAC, "LDY", abs, 3, 4, false}, {0xBC, "LDY", abx, 3, 4, true},
+	{0x4A, "LSR", acc, 1, 2, false}, {0x46, "LSR", zp0, 2, 5, false}, {0x56, "LSR", zpx, 2, 6, false}, {0x4E, "LSR", abs, 3, 6, false}, {0x5E, "LSR", abx, 3, 7, false},
+	{0xEA, "NOP", imp, 1, 2, false},
+	{0x09, "ORA", imm, 2, 2, false}, {0x05, "ORA", zp0, 2, 3, false}, {0x15, "ORA", zpx, 2, 4, false}, {0x0D, "ORA", abs, 3, 4, false},
+	{0x1D, "ORA", abx, 3, 4, true}, {0x19, "ORA", aby, 3, 4, true}, {0x01, "ORA", izx, 2, 6, false}, {0x11, "ORA", izy, 2, 5, true},
+	{0x48, "PHA", imp, 1, 3, false}, {0x08, "PHP", imp, 1, 3, false}, {0x68, "PLA", imp, 1, 4, false}, {0x28, "PLP", imp, 1, 4, false},
+	{0x2A, "ROL", acc, 1, 2, false}, {0x26, "ROL", zp0, 2, 5, false}, {0x36, "ROL", zpx, 2, 6, false}, {0x2E, "ROL", abs, 3, 6, false}, {0x3E, "ROL", abx, 3, 7, false},
+	{0x6A, "ROR", acc, 1, 2, false}, {0x66, "ROR", zp0, 2, 5, false}, {0x76, "ROR", zpx, 2, 6, false}, {0x6E, "ROR", abs, 3, 6, false}, {0x7E, "ROR", abx, 3, 7, false},
+	{0x40, "RTI", imp, 1, 6, false}, {0x60, "RTS", imp, 1, 6, false},
+	{0xE9, "SBC", imm, 2, 2, false}, {0xE5, "SBC", zp0, 2, 3, false}, {0xF5, "SBC", zpx, 2, 4, false}, {0xED, "SBC", abs, 3, 4, false},
+	{0xFD, "SBC", abx, 3, 4, true}, {0xF9, "SBC", aby, 3, 4, true}, {0xE1, "SBC", izx, 2, 6, false}, {0xF1, "SBC", izy, 2, 5, true},
+	{0x38, "SEC", imp, 1, 2, false}, {0xF8, "SED", imp, 1, 2, false}, {0x78, "SEI", imp, 1, 2, false},
+	{0x85, "STA", zp0, 2, 3, false}, {0x95, "STA", zpx, 2, 4, false}, {0x8D, "STA", abs, 3, 4, false}, {0x9D, "STA", abx, 3, 5, false},
+	{0x99, "STA", aby, 3, 5, false}, {0x81, "STA", izx, 2, 6, false}, {0x91, "STA", izy, 2, 6, false},
+	{0x86, "STX", zp0, 2, 3, false}, {0x96, "STX", zpy, 2, 4, false}, {0x8E, "STX", abs, 3, 4, false},
+	{0x84, "STY", zp0, 2, 3, false}, {0x94, "STY", zpx, 2, 4, false}, {0x8C, "STY", abs, 3, 4, false},
+	{0xAA, "TAX", imp, 1, 2, false}, {0xA8, "TAY", imp, 1, 2, false}, {0xBA, "TSX", imp, 1, 2, false},
+	{0x8A, "TXA", imp, 1, 2, false}, {0x9A, "TXS", imp, 1, 2, false}, {0x98, "TYA", imp, 1, 2, false},
+}
+
+// flagCase runs an instruction with every byte of memory, operands
+// included, set to operand, so any addressing mode reads it. status is the
+// expected result, ignoring the B and unused bits.
+type flagCase struct {
+	a, x, y, sp uint8
+	status      Flag
+	operand     uint8
+	expected    Flag
+}
+
+var flagCases = map[string]flagCase{
+	"ADC": {a: 0x50, operand: 0x50, expected: negative | overflow},
+	"AND": {a: 0xF0, operand: 0x0F, expected: zero},
+	"ASL": {a: 0x81, operand: 0x81, expected: carryBit},
+	"BCC": {status: carryBit, expected: carryBit},
+	"BCS": {},
+	"BEQ": {},
+	"BMI": {},
+	"BNE": {status: zero, expected: zero},
+	"BPL": {status: negative, expected: negative},
+	"BVC": {status: overflow, expected: overflow},
+	"BVS": {},
+	"BIT": {a: 0x01, operand: 0xC0, expected: negative | overflow | zero},
+	"BRK": {expected: disableInterrupts},
+	"CLC": {status: carryBit},
+	"CLD": {status: decimalMode},
+	"CLI": {status: disableInterrupts},
+	"CLV": {status: overflow},
+	"CMP": {a: 0x40, operand: 0x40, expected: zero | carryBit},
+	"CPX": {x: 0x40, operand: 0x40, expected: zero | carryBit},
+	"CPY": {y: 0x40, operand: 0x40, expected: zero | carryBit},
+	"DEC": {operand: 0x01, expected: zero},
+	"DEX": {x: 0x01, expected: zero},
+	"DEY": {y: 0x01, expected: zero},
+	"EOR": {a: 0xFF, operand: 0x7F, expected: negative},
+	"INC": {operand: 0xFF, expected: zero},
+	"INX": {x: 0xFF, expected: zero},
+	"INY": {y: 0xFF, expected: zero},
+	"JMP": {status: carryBit, expected: carryBit},
+	"JSR": {status: carryBit, expected: carryBit},
+	"LDA": {operand: 0x80, expected: negative},
+	"LDX": {operand: 0x80, expected: negative},
+	"LDY": {operand: 0x80, expected: negative},
+	"LSR": {a: 0x01, operand: 0x01, expected: zero | carryBit},
+	"NOP": {status: carryBit, expected: carryBit},
+	"ORA": {expected: zero},
+	"PHA": {a: 0x80, status: carryBit, expected: carryBit},
+	"PHP": {status: carryBit, expected: carryBit},
+	"PLA": {operand: 0x80, expected: negative},
+	"PLP": {operand: 0xCF, expected: 0xCF},
+	"ROL": {a: 0x80, operand: 0x80, status: carryBit, expected: carryBit},
+	"ROR": {a: 0x01, operand: 0x01, status: carryBit, expected: negative | carryBit},
+	"RTI": {operand: 0xC3, expected: 0xC3},
+	"RTS": {status: carryBit, expected: carryBit},
+	"SBC": {a: 0x50, operand: 0xB0, status: carryBit, expected: negative | overflow},
+	"SEC": {expected: carryBit},
+	"SED": {expected: decimalMode},
+	"SEI": {expected: disableInterrupts},
+	"STA": {a: 0x80, status: carryBit, expected: carryBit},
+	"STX": {x: 0x80, status: carryBit, expected: carryBit},
+	"STY": {y: 0x80, status: carryBit, expected: carryBit},
+	"TAX": {x: 0x40, expected: zero},
+	"TAY": {y: 0x40, expected: zero},
+	"TSX": {sp: 0x80, expected: negative},
+	"TXA": {x: 0x80, expected: negative},
+	"TXS": {status: carryBit, expected: carryBit},
+	"TYA": {y: 0x80, expected: negative},
+}
+
+const testProgramAddr uint16 = 0x0200
+
+func newTestCPU(memory *FlatMemory, cycleAccurate bool) *CPU6502 {
+	cpu := NewCPU6502()
+	cpu.ConnectBus(memory)
+	cpu.EnableCycleAccurateExecution(cycleAccurate)
+	cpu.programCounterReg = testProgramAddr
+	cpu.stackPointerReg = 0xFD
+	cpu.statusReg = unused
+	return cpu
+}
+
+// runInstruction clocks the CPU through one instruction and returns how
+// many cycles it took.
+func runInstruction(cpu *CPU6502) uint8 {
+	var cycles uint8
+	for cycles == 0 || !cpu.InstructionComplete() {
+		cpu.ClockSignal()
+		cycles++
+	}
+	return cycles
+}
+
+func executionModes() map[string]bool {
+	return map[string]bool{"instruction": false, "cycle": true}
+}
+
+func TestLookupMatchesDatasheet(t *testing.T) {
+	isOfficial := map[uint8]bool{}
+
+	for _, expected := range officialOpcodes {
+		isOfficial[expected.opCode] = true
+		actual := lookup[expected.opCode]
+
+		if actual.name != expected.name || actual.addressingMode != expected.addressingMode || actual.requiredAmountOfClockCycles != expected.cycles || actual.unofficial {
+			t.Errorf("$%02X: got %s mode %d, %d cycles, unofficial %v; want %s mode %d, %d cycles",
+				expected.opCode, actual.name, actual.addressingMode, actual.requiredAmountOfClockCycles, actual.unofficial,
+				expected.name, expected.addressingMode, expected.cycles)
+		}
+
+		if length := 1 + actual.addressingMode.operandLength(); length != expected.length {
+			t.Errorf("$%02X %s: length %d, want %d", expected.opCode, expected.name, length, expected.length)
+		}
+	}
+
+	if len(isOfficial) != 151 {
+		t.Fatalf("datasheet table has %d distinct opcodes, want 151", len(isOfficial))
+	}
+
+	for opCode, actual := range lookup {
+		if !isOfficial[uint8(opCode)] && !actual.unofficial {
+			t.Errorf("$%02X %s is not an official opcode", opCode, actual.name)
+		}
+	}
+}
+
+func TestOfficialOpcodesExecute(t *testing.T) {
+	for mode, cycleAccurate := range executionModes() {
+		for _, expected := range officialOpcodes {
+			flags, ok := flagCases[expected.name]
+			if !ok {
+				t.Fatalf("%s has no flag case", expected.name)
+			}
+
+			memory := NewFlatMemory()
+			for i := range memory.RAM {
+				memory.RAM[i] = flags.operand
+			}
+			memory.RAM[testProgramAddr] = expected.opCode
+
+			cpu := newTestCPU(memory, cycleAccurate)
+			cpu.accumulatorReg, cpu.xReg, cpu.yReg = flags.a, flags.x, flags.y
+			cpu.statusReg |= flags.status
+			if flags.sp != 0 {
+				cpu.stackPointerReg = flags.sp
+			}
+
+			cycles := runInstruction(cpu)
+
+			if cycles != expected.cycles {
+				t.Errorf("%s $%02X %s: %d cycles, want %d", mode, expected.opCode, expected.name, cycles, expected.cycles)
+			}
+
+			if status := cpu.statusReg &^ (break_ | unused); status != flags.expected {
+				t.Errorf("%s $%02X %s: status %08b, want %08b", mode, expected.opCode, expected.name, status, flags.expected)
+			}
+
+			switch expected.name {
+			case "BRK", "JMP", "JSR", "RTI", "RTS":
+				continue
+			}
+
+			if pc := cpu.programCounterReg; pc != testProgramAddr+expected.length {
+				t.Errorf("%s $%02X %s: PC $%04X, want $%04X", mode, expected.opCode, expected.name, pc, testProgramAddr+expected.length)
+			}
+		}
+	}
+}
+
+func TestIndexedPageCrossPenalty(t *testing.T) {
+	for mode, cycleAccurate := range executionModes() {
+		for _, expected := range append(officialOpcodes, stableUnofficialOpcodes...) {
+			if !expected.addressingMode.isIndexedWithFixup() {
+				continue
+			}
+
+			for _, index := range []uint8{0x00, 0x20} {
+				memory := NewFlatMemory()
+				if expected.addressingMode == izy {
+					memory.Load(testProgramAddr, []uint8{expected.opCode, 0x40})
+					memory.Load(0x0040, []uint8{0xF0, 0x03})
+				} else {
+					memory.Load(testProgramAddr, []uint8{expected.opCode, 0xF0, 0x03})
+				}
+
+				cpu := newTestCPU(memory, cycleAccurate)
+				cpu.xReg, cpu.yReg = index, index
+
+				want := expected.cycles
+				if index != 0 && expected.pageCrossPenalty {
+					want++
+				}
+
+				if cycles := runInstruction(cpu); cycles != want {
+					t.Errorf("%s $%02X %s index $%02X: %d cycles, want %d", mode, expected.opCode, expected.name, index, cycles, want)
+				}
+			}
+		}
+	}
+}
+
+func TestBranchCycles(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset uint8
+		cycles uint8
+	}{
+		{"not taken", 0x10, 2},
+		{"taken", 0x10, 3},
+		{"taken across a page", 0xF0, 4},
+	}
+
+	for mode, cycleAccurate := range executionModes() {
+		for _, test := range tests {
+			memory := NewFlatMemory()
+			memory.Load(testProgramAddr, []uint8{0xF0, test.offset}) // BEQ
+
+			cpu := newTestCPU(memory, cycleAccurate)
+			if test.name != "not taken" {
+				cpu.statusReg |= zero
+			}
+
+			if cycles := runInstruction(cpu); cycles != test.cycles {
+				t.Errorf("%s BEQ %s: %d cycles, want %d", mode, test.name, cycles, test.cycles)
+			}
+		}
+	}
+}
+
+// stableUnofficialOpcodes are the unofficial opcodes whose behavior does
+// not depend on analog effects, the ones games and test ROMs rely on.
+var stableUnofficialOpcodes = []opcodeSpec{
+	{0x03, "SLO", izx, 2, 8, false}, {0x07, "SLO", zp0, 2, 5, false}, {0x0F, "SLO", abs, 3, 6, false}, {0x13, "SLO", izy, 2, 8, false},
+	{0x17, "SLO", zpx, 2, 6, false}, {0x1B, "SLO", aby, 3, 7, false}, {0x1F, "SLO", abx, 3, 7, false},
+	{0x23, "RLA", izx, 2, 8, false}, {0x27, "RLA", zp0, 2, 5, false}, {0x2F, "RLA", abs, 3, 6, false}, {0x33, "RLA", izy, 2, 8, false},
+	{0x37, "RLA", zpx, 2, 6, false}, {0x3B, "RLA", aby, 3, 7, false}, {0x3F, "RLA", abx, 3, 7, false},
+	{0x43, "SRE", izx, 2, 8, false}, {0x47, "SRE", zp0, 2, 5, false}, {0x4F, "SRE", abs, 3, 6, false}, {0x53, "SRE", izy, 2, 8, false},
+	{0x57, "SRE", zpx, 2, 6, false}, {0x5B, "SRE", aby, 3, 7, false}, {0x5F, "SRE", abx, 3, 7, false},
+	{0x63, "RRA", izx, 2, 8, false}, {0x67, "RRA", zp0, 2, 5, false}, {0x6F, "RRA", abs, 3, 6, false}, {0x73, "RRA", izy, 2, 8, false},
+	{0x77, "RRA", zpx, 2, 6, false}, {0x7B, "RRA", aby, 3, 7, false}, {0x7F, "RRA", abx, 3, 7, false},
+	{0xC3, "DCP", izx, 2, 8, false}, {0xC7, "DCP", zp0, 2, 5, false}, {0xCF, "DCP", abs, 3, 6, false}, {0xD3, "DCP", izy, 2, 8, false},
+	{0xD7, "DCP", zpx, 2, 6, false}, {0xDB, "DCP", aby, 3, 7, false}, {0xDF, "DCP", abx, 3, 7, false},
+	{0xE3, "ISC", izx, 2, 8, false}, {0xE7, "ISC", zp0, 2, 5, false}, {0xEF, "ISC", abs, 3, 6, false}, {0xF3, "ISC", izy, 2, 8, false},
+	{0xF7, "ISC", zpx, 2, 6, false}, {0xFB, "ISC", aby, 3, 7, false}, {0xFF, "ISC", abx, 3, 7, false},
+	{0xA3, "LAX", izx, 2, 6, false}, {0xA7, "LAX", zp0, 2, 3, false}, {0xAF, "LAX", abs, 3, 4, false}, {0xB3, "LAX", izy, 2, 5, true},
+	{0xB7, "LAX", zpy, 2, 4, false}, {0xBF, "LAX", aby, 3, 4, true},
+	{0x83, "SAX", izx, 2, 6, false}, {0x87, "SAX", zp0, 2, 3, false}, {0x8F, "SAX", abs, 3, 4, false}, {0x97, "SAX", zpy, 2, 4, false},
+	{0x0B, "ANC", imm, 2, 2, false}, {0x2B, "ANC", imm, 2, 2, false}, {0x4B, "ALR", imm, 2, 2, false}, {0x6B, "ARR", imm, 2, 2, false},
+	{0xCB, "AXS", imm, 2, 2, false}, {0xEB, "SBC", imm, 2, 2, false},
+	{0x1A, "NOP", imp, 1, 2, false}, {0x3A, "NOP", imp, 1, 2, false}, {0x5A, "NOP", imp, 1, 2, false}, {0x7A, "NOP", imp, 1, 2, false},
+	{0xDA, "NOP", imp, 1, 2, false}, {0xFA, "NOP", imp, 1, 2, false},
+	{0x80, "NOP", imm, 2, 2, false}, {0x82, "NOP", imm, 2, 2, false}, {0x89, "NOP", imm, 2, 2, false}, {0xC2, "NOP", imm, 2, 2, false},
+	{0xE2, "NOP", imm, 2, 2, false},
+	{0x04, "NOP", zp0, 2, 3, false}, {0x44, "NOP", zp0, 2, 3, false}, {0x64, "NOP", zp0, 2, 3, false},
+	{0x14, "NOP", zpx, 2, 4, false}, {0x34, "NOP", zpx, 2, 4, false}, {0x54, "NOP", zpx, 2, 4, false}, {0x74, "NOP", zpx, 2, 4, false},
+	{0xD4, "NOP", zpx, 2, 4, false}, {0xF4, "NOP", zpx, 2, 4, false},
+	{0x0C, "NOP", abs, 3, 4, false},
+	{0x1C, "NOP", abx, 3, 4, true}, {0x3C, "NOP", abx, 3, 4, true}, {0x5C, "NOP", abx, 3, 4, true}, {0x7C, "NOP", abx, 3, 4, true},
+	{0xDC, "NOP", abx, 3, 4, true}, {0xFC, "NOP", abx, 3, 4, true},
+}
+
+// unofficialCase extends flagCase with the registers and, for the opcodes
+// that write memory, the byte stored at the effective address.
+type unofficialCase struct {
+	flagCase
+	expectedA, expectedX uint8
+	stored               uint8
+}
+
+var unofficialCases = map[string]unofficialCase{
+	"SLO": {flagCase{a: 0x01, operand: 0x81, expected: carryBit}, 0x03, 0x00, 0x02},
+	"RLA": {flagCase{a: 0xFF, operand: 0x80, status: carryBit, expected: carryBit}, 0x01, 0x00, 0x01},
+	"SRE": {flagCase{a: 0x01, operand: 0x03, expected: zero | carryBit}, 0x00, 0x00, 0x01},
+	"RRA": {flagCase{a: 0x10, operand: 0x02, status: carryBit, expected: negative}, 0x91, 0x00, 0x81},
+	"DCP": {flagCase{a: 0x40, operand: 0x41, expected: zero | carryBit}, 0x40, 0x00, 0x40},
+	"ISC": {flagCase{a: 0x50, operand: 0xAF, status: carryBit, expected: negative | overflow}, 0xA0, 0x00, 0xB0},
+	"LAX": {flagCase{operand: 0x80, expected: negative}, 0x80, 0x80, 0x80},
+	"SAX": {flagCase{a: 0xF0, x: 0x3C, operand: 0xFF, status: carryBit, expected: carryBit}, 0xF0, 0x3C, 0x30},
+	"ANC": {flagCase{a: 0xFF, operand: 0x80, expected: negative | carryBit}, 0x80, 0x00, 0x80},
+	"ALR": {flagCase{a: 0xFF, operand: 0x03, expected: carryBit}, 0x01, 0x00, 0x03},
+	"ARR": {flagCase{a: 0xFF, operand: 0x80, expected: carryBit | overflow}, 0x40, 0x00, 0x80},
+	"AXS": {flagCase{a: 0xF0, x: 0x3C, operand: 0x10, expected: carryBit}, 0xF0, 0x20, 0x10},
+	"SBC": {flagCase{a: 0x50, operand: 0xB0, status: carryBit, expected: negative | overflow}, 0xA0, 0x00, 0xB0},
+	"NOP": {flagCase{a: 0x12, x: 0x34, operand: 0x56, status: carryBit, expected: carryBit}, 0x12, 0x34, 0x56},
+}
+
+func TestStableUnofficialOpcodes(t *testing.T) {
+	for mode, cycleAccurate := range executionModes() {
+		for _, expected := range stableUnofficialOpcodes {
+			actual := lookup[expected.opCode]
+			if actual.name != expected.name || actual.addressingMode != expected.addressingMode || actual.requiredAmountOfClockCycles != expected.cycles || !actual.unofficial {
+				t.Errorf("$%02X: got %s mode %d, %d cycles, unofficial %v; want %s mode %d, %d cycles",
+					expected.opCode, actual.name, actual.addressingMode, actual.requiredAmountOfClockCycles, actual.unofficial,
+					expected.name, expected.addressingMode, expected.cycles)
+			}
+
+			test, ok := unofficialCases[expected.name]
+			if !ok {
+				t.Fatalf("%s has no test case", expected.name)
+			}
+
+			memory := NewFlatMemory()
+			for i := range memory.RAM {
+				memory.RAM[i] = test.operand
+			}
+			memory.RAM[testProgramAddr] = expected.opCode
+
+			cpu := newTestCPU(memory, cycleAccurate)
+			cpu.accumulatorReg, cpu.xReg = test.a, test.x
+			cpu.statusReg |= test.status
+
+			if cycles := runInstruction(cpu); cycles != expected.cycles {
+				t.Errorf("%s $%02X %s: %d cycles, want %d", mode, expected.opCode, expected.name, cycles, expected.cycles)
+			}
+
+			if status := cpu.statusReg &^ (break_ | unused); status != test.expected {
+				t.Errorf("%s $%02X %s: status %08b, want %08b", mode, expected.opCode, expected.name, status, test.expected)
+			}
+
+			if cpu.accumulatorReg != test.expectedA || cpu.xReg != test.expectedX {
+				t.Errorf("%s $%02X %s: A $%02X X $%02X, want A $%02X X $%02X",
+					mode, expected.opCode, expected.name, cpu.accumulatorReg, cpu.xReg, test.expectedA, test.expectedX)
+			}
+
+			if expected.addressingMode != imp && expected.addressingMode != imm {
+				if stored := memory.RAM[cpu.absoluteAddress]; stored != test.stored {
+					t.Errorf("%s $%02X %s: stored $%02X at $%04X, want $%02X",
+						mode, expected.opCode, expected.name, stored, cpu.absoluteAddress, test.stored)
+				}
+			}
+
+			if pc := cpu.programCounterReg; pc != testProgramAddr+expected.length {
+				t.Errorf("%s $%02X %s: PC $%04X, want $%04X", mode, expected.opCode, expected.name, pc, testProgramAddr+expected.length)
+			}
+		}
+	}
+}
+
+// benchmarkLoop mixes loads, stores, arithmetic, indexed addressing and a
+// branch, the bread and butter of NES game loops.
+var benchmarkLoop = []uint8{
+	0xA2, 0x00, // LDX #$00
+	0xE8,             // loop: INX
+	0xBD, 0x00, 0x02, // LDA $0200,X
+	0x69, 0x01, // ADC #$01
+	0x9D, 0x00, 0x03, // STA $0300,X
+	0x0A,       // ASL A
+	0x85, 0x10, // STA $10
+	0xD0, 0xF3, // BNE loop
+	0x4C, 0x00, 0x04, // JMP $0400
+}
+
+// BenchmarkCPUStep executes one whole instruction per iteration.
+func BenchmarkCPUStep(b *testing.B) {
+	const programAddr = 0x0400
+
+	memory := NewFlatMemory()
+	memory.Load(programAddr, benchmarkLoop)
+	memory.Load(0xFFFC, []uint8{programAddr & 0xFF, programAddr >> 8})
+
+	cpu := NewCPU6502()
+	cpu.ConnectBus(memory)
+	cpu.ResetSignal()
+	for !cpu.InstructionComplete() {
+		cpu.ClockSignal()
+	}
+
+	b.ResetTimer()
+	start := time.Now()
+
+	for i := 0; i < b.N; i++ {
+		cpu.ClockSignal()
+		for !cpu.InstructionComplete() {
+			cpu.ClockSignal()
+		}
+	}
+
+	b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "instr/s")
+}
+
+func TestExecutionModeSwitchWaitsForInstructionBoundary(t *testing.T) {
+	for mode, cycleAccurate := range executionModes() {
+		memory := NewFlatMemory()
+		memory.Load(testProgramAddr, []uint8{0xEE, 0x00, 0x03, 0xEA}) // INC $0300, NOP
+
+		cpu := newTestCPU(memory, cycleAccurate)
+		cpu.ClockSignal()
+		cpu.EnableCycleAccurateExecution(!cycleAccurate)
+
+		cycles := uint8(1)
+		for !cpu.InstructionComplete() {
+			cpu.ClockSignal()
+			cycles++
+		}
+
+		if cycles != 6 || memory.RAM[0x0300] != 1 || cpu.programCounterReg != testProgramAddr+3 {
+			t.Errorf("%s: INC took %d cycles, left $%02X at $0300 and PC $%04X", mode, cycles, memory.RAM[0x0300], cpu.programCounterReg)
+		}
+
+		if runInstruction(cpu); cpu.cycleAccurate != !cycleAccurate {
+			t.Errorf("%s: mode not switched on the instruction boundary", mode)
+		}
+	}
+}
+
+const (
+	testIRQHandlerAddr uint16 = 0x0300
+	testNMIHandlerAddr uint16 = 0x0380
+)
+
+// interruptTest runs program on a memory filled with NOPs. Before cycle n,
+// counted from 0 at the first cycle of the program, the IRQ line is held
+// from irqFrom on and an NMI edge is signalled when n is nmiAt; -1 leaves a
+// line alone.
+type interruptTest struct {
+	name     string
+	program  []uint8
+	status   Flag
+	irqFrom  int
+	nmiAt    int
+	setup    func(memory *FlatMemory, cpu *CPU6502)
+	handler  uint16
+	returnTo uint16
+	pushed   Flag // the B and I bits of the pushed status
+}
+
+// run clocks the CPU until it reaches an interrupt handler on an instruction
+// boundary and returns the handler, the return address and the status
+// pushed on the stack.
+func (this interruptTest) run(t *testing.T, cycleAccurate bool) (uint16, uint16, Flag) {
+	memory := NewFlatMemory()
+	for i := range memory.RAM {
+		memory.RAM[i] = 0xEA
+	}
+	memory.Load(testProgramAddr, this.program)
+	memory.Load(0xFFFA, []uint8{uint8(testNMIHandlerAddr & 0xFF), uint8(testNMIHandlerAddr >> 8)})
+	memory.Load(0xFFFE, []uint8{uint8(testIRQHandlerAddr & 0xFF), uint8(testIRQHandlerAddr >> 8)})
+
+	cpu := newTestCPU(memory, cycleAccurate)
+	cpu.statusReg |= this.status
+	if this.setup != nil {
+		this.setup(memory, cpu)
+	}
+
+	for cycle := 0; cycle < 100; cycle++ {
+		if this.irqFrom >= 0 && cycle >= this.irqFrom {
+			cpu.InterruptRequestSignal()
+		}
+		if cycle == this.nmiAt {
+			cpu.NonMaskableInterruptRequestSignal()
+		}
+
+		cpu.ClockSignal()
+
+		pc := cpu.programCounterReg
+		if cpu.InstructionComplete() && (pc == testIRQHandlerAddr || pc == testNMIHandlerAddr) {
+			stack := 0x0100 | uint16(cpu.stackPointerReg)
+			returnTo := uint16(memory.RAM[stack+3])<<8 | uint16(memory.RAM[stack+2])
+			return pc, returnTo, Flag(memory.RAM[stack+1])
+		}
+	}
+
+	t.Fatalf("%s: no interrupt within 100 cycles", this.name)
+	return 0, 0, 0
+}
+
+func TestInterruptPolling(t *testing.T) {
+	tests := []interruptTest{
+		// LDA $0400 takes cycles 0-3 and polls at the end of cycle 2.
+		{name: "IRQ before the last cycle", program: []uint8{0xAD, 0x00, 0x04}, irqFrom: 2, nmiAt: -1,
+			handler: testIRQHandlerAddr, returnTo: 0x0203},
+		{name: "IRQ on the last cycle", program: []uint8{0xAD, 0x00, 0x04}, irqFrom: 3, nmiAt: -1,
+			handler: testIRQHandlerAddr, returnTo: 0x0204},
+		{name: "NMI before the last cycle", program: []uint8{0xAD, 0x00, 0x04}, irqFrom: -1, nmiAt: 2,
+			handler: testNMIHandlerAddr, returnTo: 0x0203},
+		{name: "NMI on the last cycle", program: []uint8{0xAD, 0x00, 0x04}, irqFrom: -1, nmiAt: 3,
+			handler: testNMIHandlerAddr, returnTo: 0x0204},
+
+		// CLI, SEI and PLP change I after the poll, so they take effect one
+		// instruction late.
+		{name: "CLI", program: []uint8{0x58}, status: disableInterrupts, irqFrom: 0, nmiAt: -1,
+			handler: testIRQHandlerAddr, returnTo: 0x0202},
+		{name: "SEI", program: []uint8{0x78}, irqFrom: 0, nmiAt: -1,
+			handler: testIRQHandlerAddr, returnTo: 0x0201, pushed: disableInterrupts},
+		{name: "PLP", program: []uint8{0x28}, status: disableInterrupts, irqFrom: 0, nmiAt: -1,
+			setup: func(memory *FlatMemory, cpu *CPU6502) {
+				memory.RAM[0x01FE] = uint8(unused)
+			},
+			handler: testIRQHandlerAddr, returnTo: 0x0202},
+
+		// RTI restores I before the poll, so the IRQ comes before the
+		// instruction it returns to.
+		{name: "RTI", program: []uint8{0x40}, status: disableInterrupts, irqFrom: 0, nmiAt: -1,
+			setup: func(memory *FlatMemory, cpu *CPU6502) {
+				cpu.stackPointerReg = 0xFC
+				memory.Load(0x01FD, []uint8{uint8(unused), 0x10, 0x02})
+			},
+			handler: testIRQHandlerAddr, returnTo: 0x0210},
+
+		// BEQ +0 taken takes cycles 0-2 but does not poll on its extra
+		// cycle; crossing a page it takes cycles 0-3 and polls on cycle 2.
+		{name: "taken branch", program: []uint8{0xF0, 0x00}, status: zero, irqFrom: 0, nmiAt: -1,
+			handler: testIRQHandlerAddr, returnTo: 0x0202},
+		{name: "taken branch, IRQ on its extra cycle", program: []uint8{0xF0, 0x00}, status: zero, irqFrom: 1, nmiAt: -1,
+			handler: testIRQHandlerAddr, returnTo: 0x0203},
+		{name: "taken branch across a page", program: []uint8{0xF0, 0xF0}, status: zero, irqFrom: 2, nmiAt: -1,
+			handler: testIRQHandlerAddr, returnTo: 0x01F2},
+		{name: "branch not taken", program: []uint8{0xF0, 0x00}, irqFrom: 0, nmiAt: -1,
+			handler: testIRQHandlerAddr, returnTo: 0x0202},
+
+		// An NMI that arrives too late to be polled still takes over the
+		// vector fetch of the BRK or IRQ sequence that follows.
+		{name: "NMI hijacks BRK", program: []uint8{0xEA, 0x00, 0x00}, irqFrom: -1, nmiAt: 1,
+			handler: testNMIHandlerAddr, returnTo: 0x0203, pushed: break_},
+		{name: "NMI hijacks IRQ", program: []uint8{0xEA}, irqFrom: 0, nmiAt: 1,
+			handler: testNMIHandlerAddr, returnTo: 0x0201},
+		{name: "BRK", program: []uint8{0x00, 0x00}, irqFrom: -1, nmiAt: -1,
+			handler: testIRQHandlerAddr, returnTo: 0x0202, pushed: break_},
+	}
+
+	for mode, cycleAccurate := range executionModes() {
+		for _, test := range tests {
+			handler, returnTo, pushed := test.run(t, cycleAccurate)
+
+			if handler != test.handler || returnTo != test.returnTo {
+				t.Errorf("%s %s: handler $%04X returning to $%04X, want $%04X returning to $%04X",
+					mode, test.name, handler, returnTo, test.handler, test.returnTo)
+			}
+
+			if pushed &= break_ | disableInterrupts; pushed != test.pushed {
+				t.Errorf("%s %s: pushed B and I %08b, want %08b", mode, test.name, pushed, test.pushed)
+			}
+		}
+	}
+}
+
+// In cycle-accurate mode the vector is picked on the fifth cycle of BRK, so
+// an NMI arriving while BRK pushes the return address still hijacks it, and
+// is not serviced a second time.
+func TestNMIHijacksBRKMidInstruction(t *testing.T) {
+	test := interruptTest{name: "NMI during BRK", program: []uint8{0x00, 0x00}, irqFrom: -1, nmiAt: 3}
+
+	handler, returnTo, pushed := test.run(t, true)
+
+	if handler != testNMIHandlerAddr || returnTo != 0x0202 || pushed&break_ == 0 {
+		t.Errorf("handler $%04X returning to $%04X, pushed %08b; want the NMI handler returning to $0202 with B set",
+			handler, returnTo, pushed)
+	}
+}
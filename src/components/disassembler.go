@@ -0,0 +1,77 @@
+package components
+
+import "fmt"
+
+// DisassembleInstruction decodes the instruction at addr without side effects
+// and returns its raw bytes and its text in the Nintendulator trace format,
+// including the effective address and the value currently stored there.
+func (this *CPU6502) DisassembleInstruction(addr uint16) ([]uint8, string) {
+	opCode := this.read(addr, true)
+	name := lookup[opCode].name
+	mode := lookup[opCode].addressingMode
+	length := 1 + mode.operandLength()
+
+	instructionBytes := make([]uint8, length)
+	for i := uint16(0); i < length; i++ {
+		instructionBytes[i] = this.read(addr+i, true)
+	}
+
+	var lowByte, highByte uint8
+	if length > 1 {
+		lowByte = instructionBytes[1]
+	}
+	if length > 2 {
+		highByte = instructionBytes[2]
+	}
+	operand := uint16(highByte)<<8 | uint16(lowByte)
+
+	var text string
+	switch mode {
+	case imp:
+		text = name
+	case acc:
+		text = name + " A"
+	case imm:
+		text = fmt.Sprintf("%s #$%02X", name, lowByte)
+	case zp0:
+		text = fmt.Sprintf("%s $%02X = %02X", name, lowByte, this.read(uint16(lowByte), true))
+	case zpx:
+		effectiveAddress := lowByte + this.xReg
+		text = fmt.Sprintf("%s $%02X,X @ %02X = %02X", name, lowByte, effectiveAddress, this.read(uint16(effectiveAddress), true))
+	case zpy:
+		effectiveAddress := lowByte + this.yReg
+		text = fmt.Sprintf("%s $%02X,Y @ %02X = %02X", name, lowByte, effectiveAddress, this.read(uint16(effectiveAddress), true))
+	case rel:
+		relativeAddress := uint16(lowByte)
+		if relativeAddress&0x80 != 0 {
+			relativeAddress |= 0xFF00
+		}
+		text = fmt.Sprintf("%s $%04X", name, addr+2+relativeAddress)
+	case abs:
+		if opCode == 0x4C || opCode == 0x20 {
+			text = fmt.Sprintf("%s $%04X", name, operand)
+		} else {
+			text = fmt.Sprintf("%s $%04X = %02X", name, operand, this.read(operand, true))
+		}
+	case abx:
+		effectiveAddress := operand + uint16(this.xReg)
+		text = fmt.Sprintf("%s $%04X,X @ %04X = %02X", name, operand, effectiveAddress, this.read(effectiveAddress, true))
+	case aby:
+		effectiveAddress := operand + uint16(this.yReg)
+		text = fmt.Sprintf("%s $%04X,Y @ %04X = %02X", name, operand, effectiveAddress, this.read(effectiveAddress, true))
+	case ind:
+		// Reproduces the page wrap bug of JMP ($xxFF).
+		target := uint16(this.read((operand&0xFF00)|((operand+1)&0x00FF), true))<<8 | uint16(this.read(operand, true))
+		text = fmt.Sprintf("%s ($%04X) = %04X", name, operand, target)
+	case izx:
+		pointer := lowByte + this.xReg
+		effectiveAddress := uint16(this.read(uint16(pointer+1), true))<<8 | uint16(this.read(uint16(pointer), true))
+		text = fmt.Sprintf("%s ($%02X,X) @ %02X = %04X = %02X", name, lowByte, pointer, effectiveAddress, this.read(effectiveAddress, true))
+	case izy:
+		baseAddress := uint16(this.read(uint16(lowByte+1), true))<<8 | uint16(this.read(uint16(lowByte), true))
+		effectiveAddress := baseAddress + uint16(this.yReg)
+		text = fmt.Sprintf("%s ($%02X),Y = %04X @ %04X = %02X", name, lowByte, baseAddress, effectiveAddress, this.read(effectiveAddress, true))
+	}
+
+	return instructionBytes, text
+}
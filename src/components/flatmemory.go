@@ -0,0 +1,31 @@
+package components
+
+const FLAT_MEMORY_SIZE = 64 * 1024
+
+var _ CPUBus = (*FlatMemory)(nil)
+
+// FlatMemory is a plain 64KB RAM without any memory map, for running generic
+// 6502 programs on CPU6502 outside of the NES.
+type FlatMemory struct {
+	RAM [FLAT_MEMORY_SIZE]uint8
+}
+
+func NewFlatMemory() *FlatMemory {
+	return &FlatMemory{}
+}
+
+func (this *FlatMemory) CPUWrite(addr uint16, data uint8) {
+	this.RAM[addr] = data
+}
+
+func (this *FlatMemory) CPURead(addr uint16, readOnly bool) uint8 {
+	return this.RAM[addr]
+}
+
+// Load copies an image into memory starting at addr, wrapping around at the
+// end of the address space.
+func (this *FlatMemory) Load(addr uint16, image []uint8) {
+	for i, data := range image {
+		this.RAM[addr+uint16(i)] = data
+	}
+}
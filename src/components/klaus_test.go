@@ -0,0 +1,110 @@
+package components
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// Klaus Dormann's 6502 test suites report their result by trapping, i.e.
+// jumping or branching to themselves. The functional test traps on the
+// success address when everything passed and next to the failing check
+// otherwise, with the current test number kept at $0200. The decimal test
+// always traps at its end and leaves ERROR at $000B set to 0 on success.
+// Neither binary is distributed with the repository.
+const (
+	klausFunctionalPath         = "testdata/6502_functional_test.bin"
+	klausFunctionalLoadAddr     = 0x0000
+	klausFunctionalStartAddr    = 0x0400
+	klausFunctionalSuccessAddr  = 0x3469
+	klausFunctionalTestCaseAddr = 0x0200
+	klausDecimalPath            = "testdata/6502_decimal_test.bin"
+	klausDecimalLoadAddr        = 0x0200
+	klausDecimalStartAddr       = 0x0200
+	klausDecimalErrorAddr       = 0x000B
+	klausMaxInstructions        = 100_000_000
+)
+
+// TestKlausFunctional runs 6502_functional_test.bin assembled with the
+// default configuration, which traps at $3469 when every check passed.
+func TestKlausFunctional(t *testing.T) {
+	memory := loadKlausImage(t, klausFunctionalPath, klausFunctionalLoadAddr)
+	cpu := newKlausCPU(memory, klausFunctionalStartAddr)
+
+	trapAddr, err := runUntilTrap(cpu, klausMaxInstructions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if trapAddr != klausFunctionalSuccessAddr {
+		t.Errorf("trapped at $%04X in test case $%02X", trapAddr, memory.RAM[klausFunctionalTestCaseAddr])
+	}
+}
+
+func TestKlausDecimal(t *testing.T) {
+	memory := loadKlausImage(t, klausDecimalPath, klausDecimalLoadAddr)
+	cpu := newKlausCPU(memory, klausDecimalStartAddr)
+
+	trapAddr, err := runUntilTrap(cpu, klausMaxInstructions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if memory.RAM[klausDecimalErrorAddr] != 0 {
+		t.Errorf("trapped at $%04X with ERROR set to $%02X", trapAddr, memory.RAM[klausDecimalErrorAddr])
+	}
+}
+
+// loadKlausImage skips the test when the binary is not there.
+func loadKlausImage(t *testing.T, binPath string, loadAddr uint16) *FlatMemory {
+	image, err := os.ReadFile(binPath)
+	if errors.Is(err, os.ErrNotExist) {
+		t.Skipf("%s not found", binPath)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if int(loadAddr)+len(image) > FLAT_MEMORY_SIZE {
+		t.Fatalf("%s: image of %d bytes does not fit at $%04X", binPath, len(image), loadAddr)
+	}
+
+	memory := NewFlatMemory()
+	memory.Load(loadAddr, image)
+
+	return memory
+}
+
+func newKlausCPU(memory *FlatMemory, startAddr uint16) *CPU6502 {
+	cpu := NewCPU6502()
+	cpu.ConnectBus(memory)
+	cpu.EnableDecimalMode(true)
+	cpu.ResetSignal()
+
+	for !cpu.InstructionComplete() {
+		cpu.ClockSignal()
+	}
+	cpu.programCounterReg = startAddr
+
+	return cpu
+}
+
+// runUntilTrap executes whole instructions until one of them leaves the
+// program counter where it started, which is how 6502 test programs halt.
+func runUntilTrap(cpu *CPU6502, maxInstructions int) (uint16, error) {
+	for i := 0; i < maxInstructions; i++ {
+		instructionAddr := cpu.programCounterReg
+
+		cpu.ClockSignal()
+		for !cpu.InstructionComplete() {
+			cpu.ClockSignal()
+		}
+
+		if cpu.programCounterReg == instructionAddr {
+			return instructionAddr, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no trap after %d instructions, PC at $%04X", maxInstructions, cpu.programCounterReg)
+}
@@ -0,0 +1,106 @@
+package components
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MAPPER_HANDLED is returned as the mapped address when the mapper served
+// the access itself, from its own RAM or registers, instead of pointing
+// into PRG or CHR memory.
+const MAPPER_HANDLED uint32 = 0xFFFFFFFF
+
+// Discrete logic boards share the NES 2.0 submapper numbering for bus
+// conflicts: 1 means none, 2 means the written value is ANDed with the ROM
+// byte at the same address. 0 leaves it unspecified and is treated as none.
+const (
+	BUS_CONFLICTS_SUBMAPPER_NONE = 1
+	BUS_CONFLICTS_SUBMAPPER_AND  = 2
+)
+
+var ErrUnsupportedMapper = errors.New("unsupported mapper")
+
+// Mapper is the logic of a cartridge board. It translates CPU addresses into
+// offsets in PRG memory and PPU addresses into offsets in CHR memory; the map
+// functions return false when the board does not respond at addr. A readOnly
+// CPU read comes from a debugger and must not acknowledge anything.
+type Mapper interface {
+	CPUMapRead(addr uint16, mappedAddr *uint32, data *uint8, readOnly bool) bool
+	CPUMapWrite(addr uint16, mappedAddr *uint32, data uint8) bool
+	PPUMapRead(addr uint16, mappedAddr *uint32, data *uint8) bool
+	PPUMapWrite(addr uint16, mappedAddr *uint32, data uint8) bool
+
+	Reset()
+	Mirroring() Mirroring
+
+	// IRQState reports whether the board holds the CPU IRQ line low;
+	// IRQClear acknowledges it.
+	IRQState() bool
+	IRQClear()
+
+	// Clock is called once per CPU cycle.
+	Clock()
+}
+
+// busConflictMapper is implemented by boards whose register writes go to
+// addresses where the PRG ROM also drives the data bus.
+type busConflictMapper interface {
+	HasBusConflicts() bool
+}
+
+// ppuAddressObserver is implemented by boards that watch the PPU address
+// bus, such as MMC3 counting scanlines on A12.
+type ppuAddressObserver interface {
+	PPUAddress(addr uint16)
+}
+
+// partialBusDriver is implemented by boards that drive only some data lines
+// on a read, such as the VRC2 microwire latch on D0. The other lines keep
+// the value left on the bus.
+type partialBusDriver interface {
+	DrivenBits(addr uint16) uint8
+}
+
+// cpuDataObserver is implemented by boards that listen to the data the CPU
+// reads from the cartridge, such as the MMC5 PCM channel in read mode.
+type cpuDataObserver interface {
+	CPUData(addr uint16, data uint8)
+}
+
+// ciramSelector is implemented by boards that pick the console nametable
+// page for each of the four nametables themselves. Their Mirroring returns
+// MIRROR_MAPPER_CONTROLLED.
+type ciramSelector interface {
+	CIRAMPage(addr uint16) int
+}
+
+// expansionAudio is implemented by boards with their own sound channels.
+// AudioSample returns their mixed output on the scale of the 2A03 mixer.
+type expansionAudio interface {
+	AudioSample() float32
+}
+
+// MapperConstructor builds a mapper for the PRG and CHR sizes and board
+// variant described by header.
+type MapperConstructor func(header CartridgeHeader) Mapper
+
+var mapperRegistry = map[uint16]MapperConstructor{}
+
+// RegisterMapper makes a board available to the cartridge loader. Boards
+// register themselves from init.
+func RegisterMapper(mapperID uint16, constructor MapperConstructor) {
+	if _, exists := mapperRegistry[mapperID]; exists {
+		panic(fmt.Sprintf("mapper %d registered twice", mapperID))
+	}
+	mapperRegistry[mapperID] = constructor
+}
+
+func newMapper(header CartridgeHeader) (Mapper, error) {
+	constructor, exists := mapperRegistry[header.Mapper]
+
+	if !exists {
+		return nil, fmt.Errorf("mapper %d: %w", header.Mapper, ErrUnsupportedMapper)
+	}
+
+	return constructor(header), nil
+}
@@ -0,0 +1,103 @@
+package components
+
+// mapper000 is NROM: 16KB (NROM-128) or 32KB (NROM-256) of PRG ROM with no
+// bank switching, 8KB of CHR ROM or CHR RAM, and mirroring fixed by
+// solder pads. Family BASIC adds 2-4KB of PRG RAM at $6000, mirrored up to
+// $7FFF.
+type mapper000 struct {
+	PRGAddressMask uint16
+	CHRIsRAM       bool
+	PRGRAM         []uint8
+	mirroring      Mirroring
+}
+
+func init() {
+	RegisterMapper(0, newMapper000)
+}
+
+func newMapper000(header CartridgeHeader) Mapper {
+	mapper := &mapper000{
+		PRGAddressMask: 0x3FFF,
+		CHRIsRAM:       header.CHRROMSize == 0,
+		mirroring:      header.Mirroring,
+	}
+
+	if header.PRGROMSize > PRG_BANK_SIZE {
+		mapper.PRGAddressMask = 0x7FFF
+	}
+
+	// Family BASIC is the only NROM game with PRG RAM, and it has a battery.
+	PRGRAMSize := header.BoardPRGRAMSize(0)
+	if PRGRAMSize > PRG_RAM_BANK_SIZE {
+		PRGRAMSize = PRG_RAM_BANK_SIZE
+	}
+
+	if PRGRAMSize > 0 {
+		mapper.PRGRAM = make([]uint8, PRGRAMSize)
+	}
+
+	return mapper
+}
+
+func (this *mapper000) CPUMapRead(addr uint16, mappedAddr *uint32, data *uint8, readOnly bool) bool {
+	if addr >= 0x6000 && addr <= 0x7FFF && this.PRGRAM != nil {
+		*mappedAddr = MAPPER_HANDLED
+		*data = this.PRGRAM[int(addr-0x6000)%len(this.PRGRAM)]
+		return true
+	}
+
+	if addr >= 0x8000 {
+		*mappedAddr = uint32(addr & this.PRGAddressMask)
+		return true
+	}
+
+	return false
+}
+
+func (this *mapper000) CPUMapWrite(addr uint16, mappedAddr *uint32, data uint8) bool {
+	if addr >= 0x6000 && addr <= 0x7FFF && this.PRGRAM != nil {
+		*mappedAddr = MAPPER_HANDLED
+		this.PRGRAM[int(addr-0x6000)%len(this.PRGRAM)] = data
+		return true
+	}
+
+	return false
+}
+
+func (this *mapper000) PPUMapRead(addr uint16, mappedAddr *uint32, data *uint8) bool {
+	if addr <= 0x1FFF {
+		*mappedAddr = uint32(addr)
+		return true
+	}
+
+	return false
+}
+
+func (this *mapper000) PPUMapWrite(addr uint16, mappedAddr *uint32, data uint8) bool {
+	if addr <= 0x1FFF && this.CHRIsRAM {
+		*mappedAddr = uint32(addr)
+		return true
+	}
+
+	return false
+}
+
+func (this *mapper000) Reset() {
+
+}
+
+func (this *mapper000) Mirroring() Mirroring {
+	return this.mirroring
+}
+
+func (this *mapper000) IRQState() bool {
+	return false
+}
+
+func (this *mapper000) IRQClear() {
+
+}
+
+func (this *mapper000) Clock() {
+
+}
@@ -0,0 +1,290 @@
+package components
+
+const (
+	MMC1_SUBMAPPER_SUROM = 1
+	MMC1_SUBMAPPER_SOROM = 2
+	MMC1_SUBMAPPER_MMC1A = 3
+	MMC1_SUBMAPPER_SXROM = 4
+
+	MMC1_PRG_OUTER_BANK_SIZE = 256 * 1024
+	MMC1_SHIFT_REGISTER_INIT = 0x10
+)
+
+// mapper001 is MMC1 (SxROM). The CPU loads its registers one bit at a time
+// through a 5-bit shift register; the fifth write to $8000-$FFFF commits the
+// value to the register picked by address bits 13-14.
+//
+// SUROM and SXROM wire CHR bank bit 4 to PRG A18 to reach 512KB of PRG ROM;
+// SOROM and SXROM use CHR bank bits 3 and 2-3 to select among 16KB or 32KB of
+// PRG RAM. Those bits are only free because these boards use 8KB of CHR RAM.
+type mapper001 struct {
+	PRGBanks   int
+	CHRBanks4K int
+	CHRIsRAM   bool
+	PRGRAM     []uint8
+	submapper  uint8
+
+	shiftRegister uint8
+	control       uint8
+	CHRBank0      uint8
+	CHRBank1      uint8
+	PRGBank       uint8
+
+	// cyclesSinceWrite implements the ignored write on back to back cycles,
+	// which read-modify-write instructions trigger with their dummy write.
+	cyclesSinceWrite uint8
+	// lastCHRHalf is PPU A12 on the last pattern fetch. In 4KB CHR mode it
+	// picks which CHR register drives the SUROM/SOROM/SXROM extra lines.
+	lastCHRHalf uint8
+}
+
+func init() {
+	RegisterMapper(1, newMapper001)
+}
+
+func newMapper001(header CartridgeHeader) Mapper {
+	mapper := &mapper001{
+		PRGBanks:   header.PRGROMSize / PRG_BANK_SIZE,
+		CHRBanks4K: (header.CHRROMSize + header.CHRRAMSize + header.CHRNVRAMSize) / (CHR_BANK_SIZE / 2),
+		CHRIsRAM:   header.CHRROMSize == 0,
+		submapper:  header.Submapper,
+	}
+
+	if mapper.CHRBanks4K == 0 {
+		mapper.CHRBanks4K = 2
+	}
+
+	PRGRAMSize := header.BoardPRGRAMSize(PRG_RAM_BANK_SIZE)
+
+	// Old headers cannot tell the variants apart, so recognize them by
+	// their memory sizes when the submapper is not set.
+	if mapper.submapper == 0 {
+		switch {
+		case PRGRAMSize == 32*1024:
+			mapper.submapper = MMC1_SUBMAPPER_SXROM
+		case PRGRAMSize == 16*1024:
+			mapper.submapper = MMC1_SUBMAPPER_SOROM
+		case header.PRGROMSize > MMC1_PRG_OUTER_BANK_SIZE:
+			mapper.submapper = MMC1_SUBMAPPER_SUROM
+		}
+	}
+
+	if PRGRAMSize > 0 {
+		mapper.PRGRAM = make([]uint8, PRGRAMSize)
+	}
+
+	mapper.Reset()
+
+	return mapper
+}
+
+func (this *mapper001) CPUMapRead(addr uint16, mappedAddr *uint32, data *uint8, readOnly bool) bool {
+	if addr >= 0x6000 && addr <= 0x7FFF {
+		if !this.isPRGRAMEnabled() {
+			return false
+		}
+
+		*mappedAddr = MAPPER_HANDLED
+		*data = this.PRGRAM[this.PRGRAMOffset(addr)]
+		return true
+	}
+
+	if addr >= 0x8000 {
+		*mappedAddr = this.PRGOffset(addr)
+		return true
+	}
+
+	return false
+}
+
+func (this *mapper001) CPUMapWrite(addr uint16, mappedAddr *uint32, data uint8) bool {
+	if addr >= 0x6000 && addr <= 0x7FFF {
+		if !this.isPRGRAMEnabled() {
+			return false
+		}
+
+		*mappedAddr = MAPPER_HANDLED
+		this.PRGRAM[this.PRGRAMOffset(addr)] = data
+		return true
+	}
+
+	if addr < 0x8000 {
+		return false
+	}
+
+	isConsecutiveWrite := this.cyclesSinceWrite < 2
+	this.cyclesSinceWrite = 0
+
+	if isConsecutiveWrite {
+		return false
+	}
+
+	if data&0x80 != 0 {
+		this.shiftRegister = MMC1_SHIFT_REGISTER_INIT
+		this.control |= 0x0C
+		return false
+	}
+
+	// The register is full when the marker bit reaches bit 0.
+	isLastWrite := this.shiftRegister&0x01 != 0
+	this.shiftRegister = this.shiftRegister>>1 | (data&0x01)<<4
+
+	if isLastWrite {
+		value := this.shiftRegister
+
+		switch (addr >> 13) & 0x03 {
+		case 0:
+			this.control = value
+		case 1:
+			this.CHRBank0 = value
+		case 2:
+			this.CHRBank1 = value
+		case 3:
+			this.PRGBank = value
+		}
+
+		this.shiftRegister = MMC1_SHIFT_REGISTER_INIT
+	}
+
+	return false
+}
+
+func (this *mapper001) PPUMapRead(addr uint16, mappedAddr *uint32, data *uint8) bool {
+	if addr <= 0x1FFF {
+		*mappedAddr = this.CHROffset(addr)
+		return true
+	}
+
+	return false
+}
+
+func (this *mapper001) PPUMapWrite(addr uint16, mappedAddr *uint32, data uint8) bool {
+	if addr <= 0x1FFF && this.CHRIsRAM {
+		*mappedAddr = this.CHROffset(addr)
+		return true
+	}
+
+	return false
+}
+
+// PRGOffset applies the banking mode in control bits 2-3: 32KB switching
+// ignoring the low bank bit, first bank fixed at $8000, or last bank fixed
+// at $C000.
+func (this *mapper001) PRGOffset(addr uint16) uint32 {
+	bank := int(this.PRGBank & 0x0F)
+	lastBank := 0x0F
+
+	var selected int
+	switch (this.control >> 2) & 0x03 {
+	case 0, 1:
+		selected = bank&0x0E | int(addr>>14)&0x01
+	case 2:
+		if addr < 0xC000 {
+			selected = 0
+		} else {
+			selected = bank
+		}
+	case 3:
+		if addr < 0xC000 {
+			selected = bank
+		} else {
+			selected = lastBank
+		}
+	}
+
+	if this.submapper == MMC1_SUBMAPPER_SUROM || this.submapper == MMC1_SUBMAPPER_SXROM {
+		selected |= int(this.extraCHRLines() & 0x10)
+	}
+
+	selected %= this.PRGBanks
+
+	return uint32(selected*PRG_BANK_SIZE) | uint32(addr&0x3FFF)
+}
+
+// CHROffset switches either one 8KB bank, ignoring the low bit of CHR bank
+// 0, or two independent 4KB banks, depending on control bit 4.
+func (this *mapper001) CHROffset(addr uint16) uint32 {
+	this.lastCHRHalf = uint8(addr>>12) & 0x01
+
+	var bank int
+	if this.control&0x10 == 0 {
+		bank = int(this.CHRBank0&0x1E) | int(this.lastCHRHalf)
+	} else if this.lastCHRHalf == 0 {
+		bank = int(this.CHRBank0 & 0x1F)
+	} else {
+		bank = int(this.CHRBank1 & 0x1F)
+	}
+
+	bank %= this.CHRBanks4K
+
+	return uint32(bank*CHR_BANK_SIZE/2) | uint32(addr&0x0FFF)
+}
+
+func (this *mapper001) PRGRAMOffset(addr uint16) int {
+	bank := 0
+
+	switch this.submapper {
+	case MMC1_SUBMAPPER_SOROM:
+		bank = int(this.extraCHRLines()>>3) & 0x01
+	case MMC1_SUBMAPPER_SXROM:
+		bank = int(this.extraCHRLines()>>2) & 0x03
+	}
+
+	return (bank*PRG_RAM_BANK_SIZE + int(addr&0x1FFF)) % len(this.PRGRAM)
+}
+
+// extraCHRLines is the CHR register whose upper bits the board rewires: CHR
+// bank 0 in 8KB mode, otherwise the one serving the last pattern fetch.
+func (this *mapper001) extraCHRLines() uint8 {
+	if this.control&0x10 != 0 && this.lastCHRHalf == 1 {
+		return this.CHRBank1
+	}
+	return this.CHRBank0
+}
+
+// isPRGRAMEnabled reads PRG bank bit 4, which MMC1B and later use to
+// disable PRG RAM. MMC1A ignores it.
+func (this *mapper001) isPRGRAMEnabled() bool {
+	if this.PRGRAM == nil {
+		return false
+	}
+	return this.submapper == MMC1_SUBMAPPER_MMC1A || this.PRGBank&0x10 == 0
+}
+
+// Reset puts the board in PRG mode 3 so the reset vector is read from the
+// last bank.
+func (this *mapper001) Reset() {
+	this.shiftRegister = MMC1_SHIFT_REGISTER_INIT
+	this.control = 0x0C
+	this.CHRBank0 = 0
+	this.CHRBank1 = 0
+	this.PRGBank = 0
+	this.cyclesSinceWrite = 2
+}
+
+func (this *mapper001) Mirroring() Mirroring {
+	switch this.control & 0x03 {
+	case 0:
+		return MIRROR_SINGLE_SCREEN_LOW
+	case 1:
+		return MIRROR_SINGLE_SCREEN_HIGH
+	case 2:
+		return MIRROR_VERTICAL
+	default:
+		return MIRROR_HORIZONTAL
+	}
+}
+
+func (this *mapper001) IRQState() bool {
+	return false
+}
+
+func (this *mapper001) IRQClear() {
+
+}
+
+func (this *mapper001) Clock() {
+	if this.cyclesSinceWrite < 2 {
+		this.cyclesSinceWrite++
+	}
+}
@@ -0,0 +1,183 @@
+package components
+
+import "testing"
+
+// testMMC1 loads an MMC1 image whose banks start with their own number.
+func testMMC1(t *testing.T, PRGBanks int, CHRBanks int, submapper uint8, RAMShift uint8) *Cartridge {
+	return testBankedCartridge(t, 1, submapper, PRGBanks, CHRBanks, RAMShift)
+}
+
+// writeMMC1 shifts value into the register at addr one bit per write, the
+// way games do, with a CPU cycle between writes.
+func writeMMC1(cart *Cartridge, addr uint16, value uint8) {
+	for i := 0; i < 5; i++ {
+		bit := value >> i & 0x01
+		cart.CPUWrite(addr, &bit)
+		cart.Clock()
+		cart.Clock()
+	}
+}
+
+func TestMMC1SerialLoad(t *testing.T) {
+	cart := testMMC1(t, 8, 1, 0, 0)
+
+	for i := 0; i < 4; i++ {
+		bit := uint8(0x03) >> i & 0x01
+		cart.CPUWrite(0xE000, &bit)
+		cart.Clock()
+		cart.Clock()
+
+		if bank := readPRGBank(cart, 0x8000); bank != 0 {
+			t.Fatalf("after %d writes $8000 is bank %d; the register must only load on the fifth", i+1, bank)
+		}
+	}
+
+	bit := uint8(0)
+	cart.CPUWrite(0xE000, &bit)
+
+	if bank := readPRGBank(cart, 0x8000); bank != 3 {
+		t.Errorf("after five writes $8000 is bank %d, want 3", bank)
+	}
+}
+
+func TestMMC1ResetBit(t *testing.T) {
+	cart := testMMC1(t, 8, 1, 0, 0)
+	writeMMC1(cart, 0x8000, 0x00) // PRG mode 0
+
+	// Two stray bits, then a write with bit 7 set clears the shift register
+	// and puts PRG mode 3 back.
+	for _, bit := range []uint8{1, 1, 0x80} {
+		cart.CPUWrite(0xE000, &bit)
+		cart.Clock()
+		cart.Clock()
+	}
+
+	writeMMC1(cart, 0xE000, 0x02)
+
+	if bank := readPRGBank(cart, 0x8000); bank != 2 {
+		t.Errorf("$8000 is bank %d, want 2 loaded from a clean shift register", bank)
+	}
+	if bank := readPRGBank(cart, 0xC000); bank != 7 {
+		t.Errorf("$C000 is bank %d, want the last bank in PRG mode 3", bank)
+	}
+}
+
+// Read-modify-write instructions write twice on back to back cycles; MMC1
+// only takes the first.
+func TestMMC1IgnoresWriteOnConsecutiveCycle(t *testing.T) {
+	cart := testMMC1(t, 16, 1, 0, 0)
+
+	for i := 0; i < 5; i++ {
+		one, zero := uint8(1), uint8(0)
+		cart.CPUWrite(0xE000, &one)
+		cart.Clock()
+		cart.CPUWrite(0xE000, &zero)
+		cart.Clock()
+		cart.Clock()
+	}
+
+	if bank := readPRGBank(cart, 0x8000); bank != 15 {
+		t.Errorf("$8000 is bank %d, want 15 from the first write of each pair", bank)
+	}
+}
+
+func TestMMC1PRGModes(t *testing.T) {
+	tests := []struct {
+		control   uint8
+		low, high uint8
+		mode      string
+	}{
+		{0x00, 4, 5, "32KB"},
+		{0x04, 4, 5, "32KB"},
+		{0x08, 0, 5, "first bank fixed"},
+		{0x0C, 5, 15, "last bank fixed"},
+	}
+
+	for _, test := range tests {
+		cart := testMMC1(t, 16, 1, 0, 0)
+		writeMMC1(cart, 0x8000, test.control)
+		writeMMC1(cart, 0xE000, 0x05)
+
+		low, high := readPRGBank(cart, 0x8000), readPRGBank(cart, 0xC000)
+		if low != test.low || high != test.high {
+			t.Errorf("control $%02X (%s): banks %d and %d, want %d and %d", test.control, test.mode, low, high, test.low, test.high)
+		}
+	}
+}
+
+func TestMMC1CHRModes(t *testing.T) {
+	cart := testMMC1(t, 2, 4, 0, 0)
+	writeMMC1(cart, 0xA000, 0x05)
+	writeMMC1(cart, 0xC000, 0x07)
+
+	// 8KB mode ignores the low bit of CHR bank 0 and CHR bank 1 entirely.
+	writeMMC1(cart, 0x8000, 0x0C)
+	if low, high := readCHRBank(cart, 0x0000), readCHRBank(cart, 0x1000); low != 4 || high != 5 {
+		t.Errorf("8KB mode: 4KB banks %d and %d, want 4 and 5", low, high)
+	}
+
+	writeMMC1(cart, 0x8000, 0x1C)
+	if low, high := readCHRBank(cart, 0x0000), readCHRBank(cart, 0x1000); low != 5 || high != 7 {
+		t.Errorf("4KB mode: 4KB banks %d and %d, want 5 and 7", low, high)
+	}
+}
+
+func TestMMC1Mirroring(t *testing.T) {
+	cart := testMMC1(t, 2, 1, 0, 0)
+
+	for control, expected := range []Mirroring{MIRROR_SINGLE_SCREEN_LOW, MIRROR_SINGLE_SCREEN_HIGH, MIRROR_VERTICAL, MIRROR_HORIZONTAL} {
+		writeMMC1(cart, 0x8000, 0x0C|uint8(control))
+
+		if mirroring := cart.Mirroring(); mirroring != expected {
+			t.Errorf("control bits %d: mirroring %d, want %d", control, mirroring, expected)
+		}
+	}
+}
+
+// SUROM takes PRG A18 from CHR bank bit 4, in both halves of 512KB.
+func TestMMC1SUROM512KBPRG(t *testing.T) {
+	cart := testMMC1(t, 32, 0, MMC1_SUBMAPPER_SUROM, 0)
+	writeMMC1(cart, 0xE000, 0x02)
+
+	if low, high := readPRGBank(cart, 0x8000), readPRGBank(cart, 0xC000); low != 2 || high != 15 {
+		t.Errorf("outer bank 0: banks %d and %d, want 2 and 15", low, high)
+	}
+
+	writeMMC1(cart, 0xA000, 0x10)
+
+	if low, high := readPRGBank(cart, 0x8000), readPRGBank(cart, 0xC000); low != 18 || high != 31 {
+		t.Errorf("outer bank 1: banks %d and %d, want 18 and 31", low, high)
+	}
+}
+
+// SOROM selects one of two 8KB PRG RAM banks with CHR bank bit 3, SXROM one
+// of four with bits 2-3.
+func TestMMC1PRGRAMBanking(t *testing.T) {
+	tests := []struct {
+		name      string
+		submapper uint8
+		RAMShift  uint8
+		banks     []uint8 // CHR bank values selecting each RAM bank
+	}{
+		{"SOROM", MMC1_SUBMAPPER_SOROM, 8, []uint8{0x00, 0x08}},
+		{"SXROM", MMC1_SUBMAPPER_SXROM, 9, []uint8{0x00, 0x04, 0x08, 0x0C}},
+	}
+
+	for _, test := range tests {
+		cart := testMMC1(t, 2, 0, test.submapper, test.RAMShift)
+
+		for i, bank := range test.banks {
+			writeMMC1(cart, 0xA000, bank)
+			data := uint8(0x40 + i)
+			cart.CPUWrite(0x6000, &data)
+		}
+
+		for i, bank := range test.banks {
+			writeMMC1(cart, 0xA000, bank)
+
+			if data, _ := cart.CPURead(0x6000, true); data != uint8(0x40+i) {
+				t.Errorf("%s: RAM bank %d reads $%02X, want $%02X", test.name, i, data, 0x40+i)
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package components
+
+// mapper002 is UxROM: a switchable 16KB PRG bank at $8000 and the last bank
+// fixed at $C000, with 8KB of CHR RAM. UNROM decodes 3 bank bits and UOROM
+// 4; the whole register is used here so oversized images work too.
+type mapper002 struct {
+	PRGBanks        int
+	CHRIsRAM        bool
+	hasBusConflicts bool
+	mirroring       Mirroring
+	PRGBank         uint8
+}
+
+func init() {
+	RegisterMapper(2, newMapper002)
+}
+
+func newMapper002(header CartridgeHeader) Mapper {
+	return &mapper002{
+		PRGBanks:        header.PRGROMSize / PRG_BANK_SIZE,
+		CHRIsRAM:        header.CHRROMSize == 0,
+		hasBusConflicts: header.Submapper == BUS_CONFLICTS_SUBMAPPER_AND,
+		mirroring:       header.Mirroring,
+	}
+}
+
+func (this *mapper002) CPUMapRead(addr uint16, mappedAddr *uint32, data *uint8, readOnly bool) bool {
+	if addr < 0x8000 {
+		return false
+	}
+
+	bank := int(this.PRGBank)
+	if addr >= 0xC000 {
+		bank = this.PRGBanks - 1
+	}
+
+	*mappedAddr = uint32((bank%this.PRGBanks)*PRG_BANK_SIZE) | uint32(addr&0x3FFF)
+	return true
+}
+
+func (this *mapper002) CPUMapWrite(addr uint16, mappedAddr *uint32, data uint8) bool {
+	if addr >= 0x8000 {
+		this.PRGBank = data
+	}
+
+	return false
+}
+
+func (this *mapper002) PPUMapRead(addr uint16, mappedAddr *uint32, data *uint8) bool {
+	if addr <= 0x1FFF {
+		*mappedAddr = uint32(addr)
+		return true
+	}
+
+	return false
+}
+
+func (this *mapper002) PPUMapWrite(addr uint16, mappedAddr *uint32, data uint8) bool {
+	if addr <= 0x1FFF && this.CHRIsRAM {
+		*mappedAddr = uint32(addr)
+		return true
+	}
+
+	return false
+}
+
+func (this *mapper002) HasBusConflicts() bool {
+	return this.hasBusConflicts
+}
+
+func (this *mapper002) Reset() {
+	this.PRGBank = 0
+}
+
+func (this *mapper002) Mirroring() Mirroring {
+	return this.mirroring
+}
+
+func (this *mapper002) IRQState() bool {
+	return false
+}
+
+func (this *mapper002) IRQClear() {
+
+}
+
+func (this *mapper002) Clock() {
+
+}
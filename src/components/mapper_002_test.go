@@ -0,0 +1,15 @@
+package components
+
+import "testing"
+
+func TestUxROMSwitchesTheLowBank(t *testing.T) {
+	cart := testBankedCartridge(t, 2, 0, 8, 0, 0)
+
+	for _, bank := range []uint8{3, 0, 6} {
+		cart.CPUWrite(0x8000, &bank)
+
+		if low, high := readPRGBank(cart, 0x8000), readPRGBank(cart, 0xC000); low != bank || high != 7 {
+			t.Errorf("bank %d: $8000 is bank %d and $C000 bank %d, want %d and 7", bank, low, high, bank)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package components
+
+// mapper003 is CNROM: NROM-style PRG with a switchable 8KB CHR ROM bank.
+// Retail boards decode 2 bank bits; the whole register is used here so
+// oversized images work too.
+type mapper003 struct {
+	PRGAddressMask  uint16
+	CHRBanks        int
+	hasBusConflicts bool
+	mirroring       Mirroring
+	CHRBank         uint8
+}
+
+func init() {
+	RegisterMapper(3, newMapper003)
+}
+
+func newMapper003(header CartridgeHeader) Mapper {
+	mapper := &mapper003{
+		PRGAddressMask:  0x3FFF,
+		CHRBanks:        (header.CHRROMSize + header.CHRRAMSize + header.CHRNVRAMSize) / CHR_BANK_SIZE,
+		hasBusConflicts: header.Submapper == BUS_CONFLICTS_SUBMAPPER_AND,
+		mirroring:       header.Mirroring,
+	}
+
+	if header.PRGROMSize > PRG_BANK_SIZE {
+		mapper.PRGAddressMask = 0x7FFF
+	}
+
+	if mapper.CHRBanks == 0 {
+		mapper.CHRBanks = 1
+	}
+
+	return mapper
+}
+
+func (this *mapper003) CPUMapRead(addr uint16, mappedAddr *uint32, data *uint8, readOnly bool) bool {
+	if addr >= 0x8000 {
+		*mappedAddr = uint32(addr & this.PRGAddressMask)
+		return true
+	}
+
+	return false
+}
+
+func (this *mapper003) CPUMapWrite(addr uint16, mappedAddr *uint32, data uint8) bool {
+	if addr >= 0x8000 {
+		this.CHRBank = data
+	}
+
+	return false
+}
+
+func (this *mapper003) PPUMapRead(addr uint16, mappedAddr *uint32, data *uint8) bool {
+	if addr <= 0x1FFF {
+		*mappedAddr = uint32((int(this.CHRBank)%this.CHRBanks)*CHR_BANK_SIZE) | uint32(addr)
+		return true
+	}
+
+	return false
+}
+
+func (this *mapper003) PPUMapWrite(addr uint16, mappedAddr *uint32, data uint8) bool {
+	return false
+}
+
+func (this *mapper003) HasBusConflicts() bool {
+	return this.hasBusConflicts
+}
+
+func (this *mapper003) Reset() {
+	this.CHRBank = 0
+}
+
+func (this *mapper003) Mirroring() Mirroring {
+	return this.mirroring
+}
+
+func (this *mapper003) IRQState() bool {
+	return false
+}
+
+func (this *mapper003) IRQClear() {
+
+}
+
+func (this *mapper003) Clock() {
+
+}
@@ -0,0 +1,16 @@
+package components
+
+import "testing"
+
+func TestCNROMSwitchesCHR(t *testing.T) {
+	cart := testBankedCartridge(t, 3, 0, 2, 4, 0)
+
+	for _, bank := range []uint8{2, 0, 3} {
+		cart.CPUWrite(0x8000, &bank)
+
+		// Each 8KB bank holds two 4KB halves numbered 2*bank and 2*bank+1.
+		if low, high := readCHRBank(cart, 0x0000), readCHRBank(cart, 0x1000); low != 2*bank || high != 2*bank+1 {
+			t.Errorf("bank %d: CHR halves %d and %d, want %d and %d", bank, low, high, 2*bank, 2*bank+1)
+		}
+	}
+}
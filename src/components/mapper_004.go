@@ -0,0 +1,338 @@
+package components
+
+const (
+	MMC3_SUBMAPPER_MMC6  = 1
+	MMC3_SUBMAPPER_MMC3A = 4
+
+	MMC6_PRG_RAM_SIZE = 1024
+
+	// MMC3_A12_LOW_CYCLES is how many CPU cycles A12 has to stay low before
+	// a rising edge clocks the scanline counter. The filter keeps the eight
+	// sprite fetches of a scanline, and the PPU's quick toggling between
+	// background and sprite tables, from counting more than once.
+	MMC3_A12_LOW_CYCLES = 3
+)
+
+// mapper004 is MMC3 (TxROM) and its MMC6 sibling (HKROM). Eight bank
+// registers select two 8KB PRG banks, two 2KB and four 1KB CHR banks; the
+// other two PRG banks are fixed to the end of the ROM. A scanline counter,
+// clocked by rising edges on PPU A12, raises an IRQ when it reaches zero.
+//
+// MMC3A (and the MMC3 "old" behavior) only raises the IRQ when the counter
+// was decremented to zero or explicitly reloaded, so a latch of zero fires
+// once instead of on every scanline.
+type mapper004 struct {
+	PRGBanks8K int
+	CHRBanks1K int
+	CHRIsRAM   bool
+	PRGRAM     []uint8
+	submapper  uint8
+	fourScreen bool
+
+	bankSelect   uint8
+	banks        [8]uint8
+	mirroring    Mirroring
+	PRGRAMAccess uint8
+
+	IRQLatch   uint8
+	IRQCounter uint8
+	IRQReload  bool
+	IRQEnabled bool
+	IRQActive  bool
+
+	cpuCycle    uint64
+	A12High     bool
+	A12LowSince uint64
+}
+
+func init() {
+	RegisterMapper(4, newMapper004)
+}
+
+func newMapper004(header CartridgeHeader) Mapper {
+	mapper := &mapper004{
+		PRGBanks8K: header.PRGROMSize / PRG_BANK_SIZE_8K,
+		CHRBanks1K: (header.CHRROMSize + header.CHRRAMSize + header.CHRNVRAMSize) / 1024,
+		CHRIsRAM:   header.CHRROMSize == 0,
+		submapper:  header.Submapper,
+		fourScreen: header.Mirroring == MIRROR_FOUR_SCREEN,
+	}
+
+	if mapper.CHRBanks1K == 0 {
+		mapper.CHRBanks1K = CHR_BANK_SIZE / 1024
+	}
+
+	if mapper.submapper == MMC3_SUBMAPPER_MMC6 {
+		mapper.PRGRAM = make([]uint8, MMC6_PRG_RAM_SIZE)
+	} else if PRGRAMSize := header.BoardPRGRAMSize(PRG_RAM_BANK_SIZE); PRGRAMSize > 0 {
+		mapper.PRGRAM = make([]uint8, PRGRAMSize)
+	}
+
+	mapper.Reset()
+
+	return mapper
+}
+
+func (this *mapper004) isMMC6() bool {
+	return this.submapper == MMC3_SUBMAPPER_MMC6
+}
+
+func (this *mapper004) CPUMapRead(addr uint16, mappedAddr *uint32, data *uint8, readOnly bool) bool {
+	if addr >= 0x6000 && addr <= 0x7FFF {
+		if this.isMMC6() {
+			return this.MMC6RAMRead(addr, mappedAddr, data)
+		}
+
+		if this.PRGRAM == nil || this.PRGRAMAccess&0x80 == 0 {
+			return false
+		}
+
+		*mappedAddr = MAPPER_HANDLED
+		*data = this.PRGRAM[int(addr&0x1FFF)%len(this.PRGRAM)]
+		return true
+	}
+
+	if addr >= 0x8000 {
+		*mappedAddr = this.PRGOffset(addr)
+		return true
+	}
+
+	return false
+}
+
+func (this *mapper004) CPUMapWrite(addr uint16, mappedAddr *uint32, data uint8) bool {
+	if addr >= 0x6000 && addr <= 0x7FFF {
+		if this.isMMC6() {
+			return this.MMC6RAMWrite(addr, mappedAddr, data)
+		}
+
+		if this.PRGRAM == nil || this.PRGRAMAccess&0xC0 != 0x80 {
+			return false
+		}
+
+		*mappedAddr = MAPPER_HANDLED
+		this.PRGRAM[int(addr&0x1FFF)%len(this.PRGRAM)] = data
+		return true
+	}
+
+	if addr < 0x8000 {
+		return false
+	}
+
+	isOdd := addr&0x0001 != 0
+
+	switch {
+	case addr <= 0x9FFF && !isOdd:
+		this.bankSelect = data
+	case addr <= 0x9FFF:
+		this.banks[this.bankSelect&0x07] = data
+	case addr <= 0xBFFF && !isOdd:
+		if data&0x01 == 0 {
+			this.mirroring = MIRROR_VERTICAL
+		} else {
+			this.mirroring = MIRROR_HORIZONTAL
+		}
+	case addr <= 0xBFFF:
+		// MMC6 ignores $A001 while its RAM is disabled in $8000.
+		if this.isMMC6() && this.bankSelect&0x20 == 0 {
+			break
+		}
+		this.PRGRAMAccess = data
+	case addr <= 0xDFFF && !isOdd:
+		this.IRQLatch = data
+	case addr <= 0xDFFF:
+		this.IRQCounter = 0
+		this.IRQReload = true
+	case !isOdd:
+		this.IRQEnabled = false
+		this.IRQActive = false
+	default:
+		this.IRQEnabled = true
+	}
+
+	return false
+}
+
+// MMC6 has 1KB of RAM inside the chip at $7000-$7FFF, mirrored, in two
+// 512-byte halves each with its own read and write enable in $A001. $8000
+// bit 5 enables the RAM as a whole. With only one half readable, reads
+// from the other return 0; with neither, the bus is left open.
+func (this *mapper004) MMC6RAMRead(addr uint16, mappedAddr *uint32, data *uint8) bool {
+	lowHalfReadable := this.PRGRAMAccess&0x20 != 0
+	highHalfReadable := this.PRGRAMAccess&0x80 != 0
+
+	if addr < 0x7000 || this.bankSelect&0x20 == 0 || (!lowHalfReadable && !highHalfReadable) {
+		return false
+	}
+
+	offset := addr & 0x03FF
+	isHighHalf := offset&0x0200 != 0
+
+	*mappedAddr = MAPPER_HANDLED
+	*data = 0
+	if (isHighHalf && highHalfReadable) || (!isHighHalf && lowHalfReadable) {
+		*data = this.PRGRAM[offset]
+	}
+	return true
+}
+
+func (this *mapper004) MMC6RAMWrite(addr uint16, mappedAddr *uint32, data uint8) bool {
+	offset := addr & 0x03FF
+	isHighHalf := offset&0x0200 != 0
+
+	writeEnable := uint8(0x10)
+	readEnable := uint8(0x20)
+	if isHighHalf {
+		writeEnable, readEnable = 0x40, 0x80
+	}
+
+	// A half is only writable while it is also readable.
+	if addr < 0x7000 || this.bankSelect&0x20 == 0 || this.PRGRAMAccess&(writeEnable|readEnable) != writeEnable|readEnable {
+		return false
+	}
+
+	*mappedAddr = MAPPER_HANDLED
+	this.PRGRAM[offset] = data
+	return true
+}
+
+func (this *mapper004) PPUMapRead(addr uint16, mappedAddr *uint32, data *uint8) bool {
+	if addr <= 0x1FFF {
+		*mappedAddr = this.CHROffset(addr)
+		return true
+	}
+
+	return false
+}
+
+func (this *mapper004) PPUMapWrite(addr uint16, mappedAddr *uint32, data uint8) bool {
+	if addr <= 0x1FFF && this.CHRIsRAM {
+		*mappedAddr = this.CHROffset(addr)
+		return true
+	}
+
+	return false
+}
+
+// PRGOffset places R6 at $8000, or at $C000 with the second to last bank
+// swapped to $8000 when bank select bit 6 is set. R7 always sits at $A000
+// and the last bank at $E000.
+func (this *mapper004) PRGOffset(addr uint16) uint32 {
+	secondLast := this.PRGBanks8K - 2
+	last := this.PRGBanks8K - 1
+
+	var bank int
+	switch (addr >> 13) & 0x03 {
+	case 0:
+		bank = int(this.banks[6])
+		if this.bankSelect&0x40 != 0 {
+			bank = secondLast
+		}
+	case 1:
+		bank = int(this.banks[7])
+	case 2:
+		bank = secondLast
+		if this.bankSelect&0x40 != 0 {
+			bank = int(this.banks[6])
+		}
+	case 3:
+		bank = last
+	}
+
+	bank %= this.PRGBanks8K
+
+	return uint32(bank*PRG_BANK_SIZE_8K) | uint32(addr&0x1FFF)
+}
+
+// CHROffset maps R0-R1 as 2KB banks at $0000-$0FFF and R2-R5 as 1KB banks
+// at $1000-$1FFF, or the other way around when bank select bit 7 is set.
+func (this *mapper004) CHROffset(addr uint16) uint32 {
+	if this.bankSelect&0x80 != 0 {
+		addr ^= 0x1000
+	}
+
+	var bank int
+	if addr < 0x1000 {
+		bank = int(this.banks[addr>>11]&0xFE) | int(addr>>10)&0x01
+	} else {
+		bank = int(this.banks[2+(addr>>10)&0x03])
+	}
+
+	bank %= this.CHRBanks1K
+
+	return uint32(bank*1024) | uint32(addr&0x03FF)
+}
+
+// PPUAddress sees every address the PPU drives and clocks the scanline
+// counter on filtered rising edges of A12.
+func (this *mapper004) PPUAddress(addr uint16) {
+	isA12High := addr&0x1000 != 0
+
+	if isA12High && !this.A12High && this.cpuCycle-this.A12LowSince >= MMC3_A12_LOW_CYCLES {
+		this.clockScanlineCounter()
+	}
+
+	if !isA12High && this.A12High {
+		this.A12LowSince = this.cpuCycle
+	}
+
+	this.A12High = isA12High
+}
+
+func (this *mapper004) clockScanlineCounter() {
+	counterWas := this.IRQCounter
+	wasReloaded := this.IRQReload
+
+	if this.IRQCounter == 0 || this.IRQReload {
+		this.IRQCounter = this.IRQLatch
+	} else {
+		this.IRQCounter--
+	}
+
+	this.IRQReload = false
+
+	if this.IRQCounter != 0 || !this.IRQEnabled {
+		return
+	}
+
+	if this.submapper == MMC3_SUBMAPPER_MMC3A && counterWas == 0 && !wasReloaded {
+		return
+	}
+
+	this.IRQActive = true
+}
+
+func (this *mapper004) Reset() {
+	this.bankSelect = 0
+	this.banks = [8]uint8{0, 2, 4, 5, 6, 7, 0, 1}
+	this.mirroring = MIRROR_VERTICAL
+	this.PRGRAMAccess = 0x80
+	if this.isMMC6() {
+		this.PRGRAMAccess = 0
+	}
+
+	this.IRQLatch = 0
+	this.IRQCounter = 0
+	this.IRQReload = false
+	this.IRQEnabled = false
+	this.IRQActive = false
+}
+
+func (this *mapper004) Mirroring() Mirroring {
+	if this.fourScreen {
+		return MIRROR_FOUR_SCREEN
+	}
+	return this.mirroring
+}
+
+func (this *mapper004) IRQState() bool {
+	return this.IRQActive
+}
+
+func (this *mapper004) IRQClear() {
+	this.IRQActive = false
+}
+
+func (this *mapper004) Clock() {
+	this.cpuCycle++
+}
@@ -0,0 +1,64 @@
+package components
+
+import "testing"
+
+func TestMMC3IRQFollowsRenderedScanlines(t *testing.T) {
+	for _, latch := range []uint8{1, 10, 100} {
+		bus := newTestBus(t, testINESImage(4, 2, 1, 0, nil))
+
+		// Background from $0000 and sprites from $1000: A12 rises once per
+		// scanline, at the first sprite pattern fetch.
+		startRendering(bus, PPU_CTRL_SPRITE_TABLE)
+		bus.RunFrame()
+		runUntilVBlank(bus)
+
+		bus.CPUWrite(0xC000, latch)
+		bus.CPUWrite(0xC001, 0)
+		bus.CPUWrite(0xE000, 0)
+		bus.CPUWrite(0xE001, 0)
+
+		if !runUntilIRQ(bus) {
+			t.Fatalf("latch %d: no IRQ", latch)
+		}
+
+		// The pre-render scanline reloads the counter, so the IRQ comes
+		// at the end of scanline latch-1.
+		if scanline, dot := bus.ppu.scanline, bus.ppu.dot; scanline != int(latch)-1 || dot < 257 || dot > 320 {
+			t.Errorf("latch %d: IRQ at scanline %d dot %d, want scanline %d during the sprite fetches", latch, scanline, dot, latch-1)
+		}
+	}
+}
+
+func TestMMC3IRQNeedsRendering(t *testing.T) {
+	bus := newTestBus(t, testINESImage(4, 2, 1, 0, nil))
+
+	bus.CPUWrite(0xC000, 1)
+	bus.CPUWrite(0xC001, 0)
+	bus.CPUWrite(0xE001, 0)
+
+	if runUntilIRQ(bus) {
+		t.Errorf("IRQ at scanline %d with rendering off", bus.ppu.scanline)
+	}
+}
+
+func TestMMC6RAMProtectNeedsRAMEnabled(t *testing.T) {
+	cart := testBankedCartridge(t, 4, MMC3_SUBMAPPER_MMC6, 2, 1, 0)
+	write := func(addr uint16, data uint8) { cart.CPUWrite(addr, &data) }
+
+	// With $8000 bit 5 clear the write to $A001 is lost, so enabling the
+	// RAM afterwards still leaves both halves protected.
+	write(0xA001, 0xF0)
+	write(0x8000, 0x20)
+	write(0x7000, 0x55)
+
+	if data, driven := cart.CPURead(0x7000, true); driven {
+		t.Errorf("$7000 reads $%02X with $A001 written while the RAM was disabled", data)
+	}
+
+	write(0xA001, 0xF0)
+	write(0x7000, 0x55)
+
+	if data, driven := cart.CPURead(0x7000, true); !driven || data != 0x55 {
+		t.Errorf("$7000 reads $%02X (driven %v), want $55", data, driven)
+	}
+}
@@ -0,0 +1,654 @@
+package components
+
+const (
+	MMC5_EXRAM_SIZE        = 1024
+	MMC5_MAX_PRG_RAM_SIZE  = 64 * 1024
+	MMC5_IDLE_CPU_CYCLES   = 3
+	MMC5_AUDIO_FRAME_CLOCK = 7457
+
+	// PPU fetches counted from the first nametable fetch of a scanline:
+	// 32 background tiles of 4 fetches each, 8 sprites of 4, then the
+	// first two tiles of the next scanline.
+	MMC5_SPRITE_FETCHES_START = 128
+	MMC5_SPRITE_FETCHES_END   = 160
+)
+
+// ExRAM modes, set in $5104.
+const (
+	MMC5_EXRAM_NAMETABLE = iota
+	MMC5_EXRAM_EXTENDED_ATTRIBUTES
+	MMC5_EXRAM_READ_WRITE
+	MMC5_EXRAM_READ_ONLY
+)
+
+// Nametable sources, two bits per nametable in $5105.
+const (
+	MMC5_NAMETABLE_CIRAM_0 = iota
+	MMC5_NAMETABLE_CIRAM_1
+	MMC5_NAMETABLE_EXRAM
+	MMC5_NAMETABLE_FILL
+)
+
+// mapper005 is MMC5 (ExROM). It has no view of the PPU's internal state, so
+// like the real chip it works out where the PPU is by watching its fetches:
+// the PPU reads the same nametable byte three times in a row across the
+// end of a scanline, and the fetch count since then tells background fetches
+// from sprite fetches. Rendering is considered over once the PPU leaves its
+// bus idle for a few CPU cycles.
+type mapper005 struct {
+	PRGBanks8K int
+	CHRBanks1K int
+	CHRIsRAM   bool
+	PRGRAM     []uint8
+	ExRAM      [MMC5_EXRAM_SIZE]uint8
+
+	PRGMode      uint8
+	CHRMode      uint8
+	PRGRAMWrite1 uint8
+	PRGRAMWrite2 uint8
+	ExRAMMode    uint8
+	nameTables   uint8
+	fillTile     uint8
+	fillColor    uint8
+	// PRGBanks holds $5113-$5117.
+	PRGBanks [5]uint8
+	// CHRBanks holds $5120-$512B with the upper bits from $5130 applied.
+	CHRBanks     [12]uint16
+	CHRUpperBits uint8
+	// lastCHRSetB is true when $5128-$512B were written after $5120-$5127.
+	// Outside 8x16 sprite rendering, the last written set maps all of CHR.
+	lastCHRSetB bool
+
+	splitControl uint8
+	splitScroll  uint8
+	splitBank    uint8
+
+	IRQTarget  uint8
+	IRQEnabled bool
+	IRQPending bool
+
+	multiplicand uint8
+	multiplier   uint8
+
+	// Snooped PPU registers.
+	sprites8x16      bool
+	renderingEnabled bool
+
+	inFrame        bool
+	scanline       int
+	lastPPUAddress uint16
+	matchingReads  int
+	fetchCounter   int
+	currentFetch   int
+	idleCycles     int
+	tileExRAM      uint8
+
+	pulse1        pulseChannel
+	pulse2        pulseChannel
+	audioCycle    uint32
+	PCMOutput     uint8
+	PCMReadMode   bool
+	PCMIRQEnabled bool
+	PCMIRQPending bool
+}
+
+func init() {
+	RegisterMapper(5, newMapper005)
+}
+
+func newMapper005(header CartridgeHeader) Mapper {
+	mapper := &mapper005{
+		PRGBanks8K: header.PRGROMSize / PRG_BANK_SIZE_8K,
+		CHRBanks1K: (header.CHRROMSize + header.CHRRAMSize + header.CHRNVRAMSize) / 1024,
+		CHRIsRAM:   header.CHRROMSize == 0,
+	}
+
+	if mapper.PRGBanks8K == 0 {
+		mapper.PRGBanks8K = 1
+	}
+
+	if mapper.CHRBanks1K == 0 {
+		mapper.CHRBanks1K = CHR_BANK_SIZE / 1024
+	}
+
+	// Boards carry 0, 8, 16 or 32KB of PRG RAM in different chip
+	// layouts, so iNES images get the full 64KB the chip can address.
+	PRGRAMSize := header.BoardPRGRAMSize(MMC5_MAX_PRG_RAM_SIZE)
+
+	if PRGRAMSize > 0 {
+		mapper.PRGRAM = make([]uint8, PRGRAMSize)
+	}
+
+	mapper.Reset()
+
+	return mapper
+}
+
+func (this *mapper005) CPUMapRead(addr uint16, mappedAddr *uint32, data *uint8, readOnly bool) bool {
+	if addr >= 0x5000 && addr <= 0x5FFF {
+		*mappedAddr = MAPPER_HANDLED
+		return this.readRegister(addr, data, readOnly)
+	}
+
+	if addr < 0x6000 {
+		return false
+	}
+
+	isRAM, offset := this.PRGOffset(addr)
+
+	if !isRAM {
+		*mappedAddr = offset
+		return true
+	}
+
+	if this.PRGRAM == nil {
+		return false
+	}
+
+	*mappedAddr = MAPPER_HANDLED
+	*data = this.PRGRAM[int(offset)%len(this.PRGRAM)]
+	return true
+}
+
+// CPUData sees the value of every cartridge read. In PCM read mode, reads
+// from $8000-$BFFF feed the PCM channel and a zero byte raises its IRQ.
+func (this *mapper005) CPUData(addr uint16, data uint8) {
+	if !this.PCMReadMode || addr < 0x8000 || addr > 0xBFFF {
+		return
+	}
+
+	if data == 0 {
+		this.PCMIRQPending = true
+	} else {
+		this.PCMOutput = data
+	}
+}
+
+func (this *mapper005) readRegister(addr uint16, data *uint8, readOnly bool) bool {
+	switch {
+	case addr == 0x5010:
+		*data = 0x00
+		if this.PCMIRQPending && this.PCMIRQEnabled {
+			*data |= 0x80
+		}
+		if this.PCMReadMode {
+			*data |= 0x01
+		}
+		if !readOnly {
+			this.PCMIRQPending = false
+		}
+	case addr == 0x5015:
+		*data = 0x00
+		if this.pulse1.lengthCounter > 0 {
+			*data |= 0x01
+		}
+		if this.pulse2.lengthCounter > 0 {
+			*data |= 0x02
+		}
+	case addr == 0x5204:
+		*data = 0x00
+		if this.IRQPending {
+			*data |= 0x80
+		}
+		if this.inFrame {
+			*data |= 0x40
+		}
+		if !readOnly {
+			this.IRQPending = false
+		}
+	case addr == 0x5205:
+		*data = uint8(uint16(this.multiplicand) * uint16(this.multiplier))
+	case addr == 0x5206:
+		*data = uint8(uint16(this.multiplicand) * uint16(this.multiplier) >> 8)
+	case addr >= 0x5C00 && this.ExRAMMode >= MMC5_EXRAM_READ_WRITE:
+		*data = this.ExRAM[addr&0x03FF]
+	default:
+		return false
+	}
+
+	return true
+}
+
+func (this *mapper005) CPUMapWrite(addr uint16, mappedAddr *uint32, data uint8) bool {
+	// The PPU registers repeat every 8 bytes up to $3FFF.
+	isPPURegister := addr >= 0x2000 && addr <= 0x3FFF
+	PPURegister := addr & 0x0007
+
+	switch {
+	case isPPURegister && PPURegister == 0x0000:
+		this.sprites8x16 = data&0x20 != 0
+	case isPPURegister && PPURegister == 0x0001:
+		this.renderingEnabled = data&0x18 != 0
+		if !this.renderingEnabled {
+			this.inFrame = false
+		}
+	case addr >= 0x5000 && addr <= 0x5FFF:
+		this.writeRegister(addr, data)
+	case addr >= 0x6000:
+		isRAM, offset := this.PRGOffset(addr)
+
+		if !isRAM || this.PRGRAM == nil || this.PRGRAMWrite1 != 0x02 || this.PRGRAMWrite2 != 0x01 {
+			return false
+		}
+
+		*mappedAddr = MAPPER_HANDLED
+		this.PRGRAM[int(offset)%len(this.PRGRAM)] = data
+		return true
+	}
+
+	return false
+}
+
+func (this *mapper005) writeRegister(addr uint16, data uint8) {
+	switch {
+	case addr <= 0x5003:
+		this.pulse1.write(addr, data)
+	case addr >= 0x5004 && addr <= 0x5007:
+		this.pulse2.write(addr, data)
+	case addr == 0x5010:
+		this.PCMReadMode = data&0x01 != 0
+		this.PCMIRQEnabled = data&0x80 != 0
+	case addr == 0x5011:
+		if !this.PCMReadMode && data != 0 {
+			this.PCMOutput = data
+		}
+	case addr == 0x5015:
+		this.pulse1.setEnabled(data&0x01 != 0)
+		this.pulse2.setEnabled(data&0x02 != 0)
+	case addr == 0x5100:
+		this.PRGMode = data & 0x03
+	case addr == 0x5101:
+		this.CHRMode = data & 0x03
+	case addr == 0x5102:
+		this.PRGRAMWrite1 = data & 0x03
+	case addr == 0x5103:
+		this.PRGRAMWrite2 = data & 0x03
+	case addr == 0x5104:
+		this.ExRAMMode = data & 0x03
+	case addr == 0x5105:
+		this.nameTables = data
+	case addr == 0x5106:
+		this.fillTile = data
+	case addr == 0x5107:
+		this.fillColor = data & 0x03
+	case addr >= 0x5113 && addr <= 0x5117:
+		this.PRGBanks[addr-0x5113] = data
+	case addr >= 0x5120 && addr <= 0x512B:
+		this.CHRBanks[addr-0x5120] = uint16(this.CHRUpperBits)<<8 | uint16(data)
+		this.lastCHRSetB = addr >= 0x5128
+	case addr == 0x5130:
+		this.CHRUpperBits = data & 0x03
+	case addr == 0x5200:
+		this.splitControl = data
+	case addr == 0x5201:
+		this.splitScroll = data
+	case addr == 0x5202:
+		this.splitBank = data
+	case addr == 0x5203:
+		this.IRQTarget = data
+	case addr == 0x5204:
+		this.IRQEnabled = data&0x80 != 0
+	case addr == 0x5205:
+		this.multiplicand = data
+	case addr == 0x5206:
+		this.multiplier = data
+	case addr >= 0x5C00:
+		this.writeExRAM(addr, data)
+	}
+}
+
+// In the two nametable modes the CPU may only write ExRAM while the PPU is
+// rendering a frame; outside the frame a write stores 0 instead of data. It
+// cannot be written at all in read-only mode.
+func (this *mapper005) writeExRAM(addr uint16, data uint8) {
+	switch this.ExRAMMode {
+	case MMC5_EXRAM_NAMETABLE, MMC5_EXRAM_EXTENDED_ATTRIBUTES:
+		if !this.inFrame {
+			data = 0
+		}
+		this.ExRAM[addr&0x03FF] = data
+	case MMC5_EXRAM_READ_WRITE:
+		this.ExRAM[addr&0x03FF] = data
+	}
+}
+
+// PRGOffset resolves addr through $5113-$5117. $6000-$7FFF always maps RAM
+// from $5113; the other registers select ROM when bit 7 is set, except
+// $5117 which is always ROM. PRG mode 0 maps one 32KB bank, mode 1 two 16KB
+// banks, mode 2 a 16KB and two 8KB banks, mode 3 four 8KB banks; the low
+// bank bits of larger banks come from the address.
+func (this *mapper005) PRGOffset(addr uint16) (bool, uint32) {
+	if addr < 0x8000 {
+		return true, uint32(this.PRGBanks[0]&0x07)*PRG_RAM_BANK_SIZE | uint32(addr&0x1FFF)
+	}
+
+	slot := uint8(addr>>13) & 0x03
+
+	var index uint8       // register of $5113-$5117 that maps addr
+	var windowBanks uint8 // size of the window addr falls in, in 8KB banks
+
+	switch this.PRGMode {
+	case 0:
+		index, windowBanks = 4, 4
+	case 1:
+		index, windowBanks = 2+slot&0x02, 2
+	case 2:
+		if slot < 2 {
+			index, windowBanks = 2, 2
+		} else {
+			index, windowBanks = 1+slot, 1
+		}
+	case 3:
+		index, windowBanks = 1+slot, 1
+	}
+
+	register := this.PRGBanks[index]
+	bank := register&0x7F&^(windowBanks-1) | slot&(windowBanks-1)
+	isRAM := index != 4 && register&0x80 == 0
+
+	if isRAM {
+		return true, uint32(bank&0x07)*PRG_RAM_BANK_SIZE | uint32(addr&0x1FFF)
+	}
+
+	return false, uint32(int(bank)%this.PRGBanks8K)*PRG_BANK_SIZE_8K | uint32(addr&0x1FFF)
+}
+
+// PPUAddress follows the PPU's fetches to find scanline starts, count
+// scanlines for the IRQ and know which fetch of the scanline comes next.
+func (this *mapper005) PPUAddress(addr uint16) {
+	this.idleCycles = 0
+
+	isNameTableFetch := addr >= 0x2000 && addr <= 0x2FFF && addr&0x03FF < 0x03C0
+	if isNameTableFetch && addr == this.lastPPUAddress {
+		this.matchingReads++
+	} else {
+		this.matchingReads = 0
+	}
+	this.lastPPUAddress = addr
+
+	if this.matchingReads == 2 && this.renderingEnabled {
+		this.detectScanline()
+		this.fetchCounter = 0
+	}
+
+	this.currentFetch = this.fetchCounter
+	this.fetchCounter++
+}
+
+func (this *mapper005) detectScanline() {
+	if !this.inFrame {
+		this.inFrame = true
+		this.scanline = 0
+		this.IRQPending = false
+		return
+	}
+
+	this.scanline++
+	if this.scanline == int(this.IRQTarget) {
+		this.IRQPending = true
+	}
+}
+
+func (this *mapper005) isSpriteFetch() bool {
+	return this.currentFetch >= MMC5_SPRITE_FETCHES_START && this.currentFetch < MMC5_SPRITE_FETCHES_END
+}
+
+// backgroundTile is the screen column and scanline of the tile a background
+// fetch belongs to. The first two tiles of a scanline are fetched at the end
+// of the previous one.
+func (this *mapper005) backgroundTile() (int, int) {
+	if this.currentFetch >= MMC5_SPRITE_FETCHES_END {
+		return (this.currentFetch - MMC5_SPRITE_FETCHES_END) / 4, this.scanline + 1
+	}
+	return this.currentFetch/4 + 2, this.scanline
+}
+
+// isInSplit reports whether a background fetch is served by the vertical
+// split region: the tiles left of the threshold in $5200, or right of it
+// with bit 6 set.
+func (this *mapper005) isInSplit() bool {
+	if this.splitControl&0x80 == 0 || this.ExRAMMode > MMC5_EXRAM_EXTENDED_ATTRIBUTES || !this.inFrame || this.isSpriteFetch() {
+		return false
+	}
+
+	tile, _ := this.backgroundTile()
+	if tile >= 32 {
+		return false
+	}
+
+	threshold := int(this.splitControl & 0x1F)
+	if this.splitControl&0x40 != 0 {
+		return tile >= threshold
+	}
+	return tile < threshold
+}
+
+// splitY is the line of the split region on screen, scrolled by $5201 and
+// wrapping at the bottom of the 30-row nametable.
+func (this *mapper005) splitY() int {
+	_, scanline := this.backgroundTile()
+	return (int(this.splitScroll) + scanline) % 240
+}
+
+func (this *mapper005) PPUMapRead(addr uint16, mappedAddr *uint32, data *uint8) bool {
+	if addr <= 0x1FFF {
+		*mappedAddr = this.CHROffset(addr)
+		return true
+	}
+
+	if addr > 0x3EFF {
+		return false
+	}
+
+	isAttributeFetch := addr&0x03FF >= 0x03C0
+
+	if this.isInSplit() {
+		tile, _ := this.backgroundTile()
+		y := this.splitY()
+
+		*mappedAddr = MAPPER_HANDLED
+		if isAttributeFetch {
+			attribute := this.ExRAM[0x03C0+(y/32)*8+tile/4]
+			shift := uint((y/16)&0x01)*4 + uint((tile/2)&0x01)*2
+			*data = replicatePalette(attribute >> shift)
+		} else {
+			*data = this.ExRAM[(y/8)*32+tile]
+		}
+		return true
+	}
+
+	if this.ExRAMMode == MMC5_EXRAM_EXTENDED_ATTRIBUTES && this.inFrame && !this.isSpriteFetch() {
+		if !isAttributeFetch {
+			this.tileExRAM = this.ExRAM[addr&0x03FF]
+		} else {
+			*mappedAddr = MAPPER_HANDLED
+			*data = replicatePalette(this.tileExRAM >> 6)
+			return true
+		}
+	}
+
+	switch this.nameTableSource(addr) {
+	case MMC5_NAMETABLE_EXRAM:
+		*mappedAddr = MAPPER_HANDLED
+		*data = 0
+		if this.ExRAMMode <= MMC5_EXRAM_EXTENDED_ATTRIBUTES {
+			*data = this.ExRAM[addr&0x03FF]
+		}
+		return true
+	case MMC5_NAMETABLE_FILL:
+		*mappedAddr = MAPPER_HANDLED
+		*data = this.fillTile
+		if isAttributeFetch {
+			*data = replicatePalette(this.fillColor)
+		}
+		return true
+	}
+
+	return false
+}
+
+func (this *mapper005) PPUMapWrite(addr uint16, mappedAddr *uint32, data uint8) bool {
+	if addr <= 0x1FFF {
+		if !this.CHRIsRAM {
+			return false
+		}
+		*mappedAddr = this.CHROffset(addr)
+		return true
+	}
+
+	if addr > 0x3EFF {
+		return false
+	}
+
+	switch this.nameTableSource(addr) {
+	case MMC5_NAMETABLE_EXRAM:
+		if this.ExRAMMode <= MMC5_EXRAM_EXTENDED_ATTRIBUTES {
+			this.ExRAM[addr&0x03FF] = data
+		}
+		*mappedAddr = MAPPER_HANDLED
+		return true
+	case MMC5_NAMETABLE_FILL:
+		*mappedAddr = MAPPER_HANDLED
+		return true
+	}
+
+	return false
+}
+
+func (this *mapper005) nameTableSource(addr uint16) uint8 {
+	return (this.nameTables >> ((addr >> 9) & 0x06)) & 0x03
+}
+
+// replicatePalette copies a 2-bit palette into all four quadrants of an
+// attribute byte, so it applies whichever quadrant the PPU picks.
+func replicatePalette(palette uint8) uint8 {
+	palette &= 0x03
+	return palette | palette<<2 | palette<<4 | palette<<6
+}
+
+// CHROffset maps pattern fetches. In the split region the 4KB bank in $5202
+// is used with the split's own fine Y; with extended attributes, each tile
+// picks a 4KB bank from its ExRAM byte. Otherwise, in 8x16 sprite mode
+// sprites use $5120-$5127 and the background $5128-$512B, and in 8x8 mode
+// the set written last maps everything.
+func (this *mapper005) CHROffset(addr uint16) uint32 {
+	if this.isInSplit() {
+		offset := int(this.splitBank)*4096 + int(addr&0x0FF8) | this.splitY()&0x07
+		return uint32(offset % (this.CHRBanks1K * 1024))
+	}
+
+	if this.ExRAMMode == MMC5_EXRAM_EXTENDED_ATTRIBUTES && this.inFrame && !this.isSpriteFetch() {
+		bank := int(this.tileExRAM&0x3F) | int(this.CHRUpperBits)<<6
+		return uint32((bank*4096 + int(addr&0x0FFF)) % (this.CHRBanks1K * 1024))
+	}
+
+	useSetB := this.lastCHRSetB
+	if this.sprites8x16 && this.inFrame {
+		useSetB = !this.isSpriteFetch()
+	}
+
+	var bank uint16
+	var bankSize uint16
+
+	if useSetB {
+		// Set B only covers 4KB and repeats it in both pattern tables.
+		switch this.CHRMode {
+		case 0:
+			bank, bankSize = this.CHRBanks[11], 8192
+		case 1:
+			bank, bankSize = this.CHRBanks[11], 4096
+		case 2:
+			bank, bankSize = this.CHRBanks[9+(addr>>11)&0x01*2], 2048
+		case 3:
+			bank, bankSize = this.CHRBanks[8+(addr>>10)&0x03], 1024
+		}
+		if this.CHRMode != 0 {
+			addr &= 0x0FFF
+		}
+	} else {
+		switch this.CHRMode {
+		case 0:
+			bank, bankSize = this.CHRBanks[7], 8192
+		case 1:
+			bank, bankSize = this.CHRBanks[3+(addr>>12)*4], 4096
+		case 2:
+			bank, bankSize = this.CHRBanks[1+(addr>>11)*2], 2048
+		case 3:
+			bank, bankSize = this.CHRBanks[addr>>10], 1024
+		}
+	}
+
+	offset := int(bank)*int(bankSize) + int(addr&(bankSize-1))
+
+	return uint32(offset % (this.CHRBanks1K * 1024))
+}
+
+func (this *mapper005) CIRAMPage(addr uint16) int {
+	return int(this.nameTableSource(addr) & 0x01)
+}
+
+// Reset maps the last 8KB bank everywhere, in PRG mode 3, so the reset
+// vector is found whatever the ROM size.
+func (this *mapper005) Reset() {
+	this.PRGMode = 3
+	this.CHRMode = 0
+	this.PRGBanks = [5]uint8{0, 0xFF, 0xFF, 0xFF, 0xFF}
+	this.CHRBanks = [12]uint16{}
+	this.PRGRAMWrite1 = 0
+	this.PRGRAMWrite2 = 0
+	this.ExRAMMode = 0
+	this.nameTables = 0
+	this.splitControl = 0
+	this.IRQEnabled = false
+	this.IRQPending = false
+	this.inFrame = false
+	this.pulse1 = pulseChannel{}
+	this.pulse2 = pulseChannel{}
+	this.PCMOutput = 0
+	this.PCMReadMode = false
+	this.PCMIRQEnabled = false
+	this.PCMIRQPending = false
+}
+
+func (this *mapper005) Mirroring() Mirroring {
+	return MIRROR_MAPPER_CONTROLLED
+}
+
+func (this *mapper005) IRQState() bool {
+	return (this.IRQPending && this.IRQEnabled) || (this.PCMIRQPending && this.PCMIRQEnabled)
+}
+
+func (this *mapper005) IRQClear() {
+	this.IRQPending = false
+	this.PCMIRQPending = false
+}
+
+// Clock notices the end of rendering and runs the audio: the pulse timers
+// every other CPU cycle, envelopes and length counters at a fixed 240Hz
+// since MMC5 has no frame counter of its own.
+func (this *mapper005) Clock() {
+	if this.idleCycles < MMC5_IDLE_CPU_CYCLES {
+		this.idleCycles++
+	} else {
+		this.inFrame = false
+		this.matchingReads = 0
+	}
+
+	this.audioCycle++
+
+	if this.audioCycle%2 == 0 {
+		this.pulse1.clockTimer()
+		this.pulse2.clockTimer()
+	}
+
+	if this.audioCycle%MMC5_AUDIO_FRAME_CLOCK == 0 {
+		this.pulse1.clockEnvelope()
+		this.pulse2.clockEnvelope()
+		this.pulse1.clockLengthCounter()
+		this.pulse2.clockLengthCounter()
+	}
+}
+
+func (this *mapper005) AudioSample() float32 {
+	return mixPulses(this.pulse1.output(), this.pulse2.output()) + mixPCM(this.PCMOutput)
+}
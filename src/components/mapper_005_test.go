@@ -0,0 +1,165 @@
+package components
+
+import "testing"
+
+func testMMC5(t *testing.T) (*Bus, *mapper005) {
+	image := testINESImage(5, 4, 2, 0, nil)
+	fillAlternatingCHR(image)
+
+	bus := newTestBus(t, image)
+
+	return bus, bus.cartridge.mapper.(*mapper005)
+}
+
+func TestMMC5Register5117IsAlwaysROM(t *testing.T) {
+	bus, _ := testMMC5(t)
+
+	bus.CPUWrite(0x5102, 0x02)
+	bus.CPUWrite(0x5103, 0x01)
+
+	// PRG mode 1: $5117 maps $C000-$FFFF. Bit 7 clear would select RAM on
+	// $5114-$5116, but not here.
+	bus.CPUWrite(0x5100, 0x01)
+	bus.CPUWrite(0x5117, 0x02)
+	bus.CPUWrite(0xC001, 0xAA)
+
+	if data := bus.CPURead(0xC001, true); data != 0x01 {
+		t.Errorf("mode 1: $C001 reads $%02X, want ROM byte $01", data)
+	}
+
+	// PRG mode 3: $5116 with bit 7 clear maps RAM at $C000-$DFFF.
+	bus.CPUWrite(0x5100, 0x03)
+	bus.CPUWrite(0x5116, 0x00)
+	bus.CPUWrite(0xC001, 0xAA)
+
+	if data := bus.CPURead(0xC001, true); data != 0xAA {
+		t.Errorf("mode 3: $C001 reads $%02X, want RAM byte $AA", data)
+	}
+}
+
+func TestMMC5SnoopsPPURegisterMirrors(t *testing.T) {
+	bus, mapper := testMMC5(t)
+
+	bus.CPUWrite(0x2008, PPU_CTRL_SPRITES_8X16)
+	bus.CPUWrite(0x3FF9, PPU_MASK_SHOW_BACKGROUND)
+
+	if !mapper.sprites8x16 || !mapper.renderingEnabled {
+		t.Errorf("8x16 sprites %v, rendering %v after writes to $2008 and $3FF9", mapper.sprites8x16, mapper.renderingEnabled)
+	}
+}
+
+func TestMMC5IRQAtTargetScanline(t *testing.T) {
+	for _, target := range []uint8{1, 64, 200} {
+		bus, _ := testMMC5(t)
+
+		startRendering(bus, 0)
+		bus.RunFrame()
+		runUntilVBlank(bus)
+
+		bus.CPUWrite(0x5203, target)
+		bus.CPUWrite(0x5204, 0x80)
+
+		if !runUntilIRQ(bus) {
+			t.Fatalf("target %d: no IRQ", target)
+		}
+
+		// The third read of the same nametable byte marks the start of a
+		// scanline, at its first fetch.
+		if scanline, dot := bus.ppu.scanline, bus.ppu.dot; scanline != int(target) || dot > 8 {
+			t.Errorf("target %d: IRQ at scanline %d dot %d, want the start of scanline %d", target, scanline, dot, target)
+		}
+	}
+}
+
+func TestMMC5VerticalSplit(t *testing.T) {
+	bus, _ := testMMC5(t)
+
+	// Every nametable and ExRAM byte is tile 0 with palette 0. The main
+	// background uses 4KB CHR bank 2 (color 2) and the split bank 1
+	// (color 1).
+	bus.CPUWrite(0x5101, 0x01)
+	bus.CPUWrite(0x5123, 0x02)
+	bus.CPUWrite(0x5104, MMC5_EXRAM_READ_WRITE)
+	for addr := uint16(0x5C00); addr < 0x6000; addr++ {
+		bus.CPUWrite(addr, 0x00)
+	}
+	bus.CPUWrite(0x5104, MMC5_EXRAM_NAMETABLE)
+
+	const splitTiles = 12
+	bus.CPUWrite(0x5200, 0x80|splitTiles)
+	bus.CPUWrite(0x5201, 0x00)
+	bus.CPUWrite(0x5202, 0x01)
+
+	writeTestPalette(bus)
+	startRendering(bus, 0)
+	bus.RunFrame()
+	bus.RunFrame()
+
+	leftSplit := func(column int) uint8 {
+		if column < splitTiles {
+			return testColor1
+		}
+		return testColor2
+	}
+	checkFirstScanline(t, bus, leftSplit)
+	checkColumns(t, bus, 1, leftSplit)
+
+	// With bit 6 set the split is right of the threshold instead.
+	bus.CPUWrite(0x5200, 0xC0|splitTiles)
+	bus.RunFrame()
+
+	rightSplit := func(column int) uint8 {
+		if column >= splitTiles {
+			return testColor1
+		}
+		return testColor2
+	}
+	checkFirstScanline(t, bus, rightSplit)
+	checkColumns(t, bus, 1, rightSplit)
+}
+
+// checkFirstScanline checks scanline 0 of a split. Its first two tiles are
+// fetched at the end of the pre-render scanline, before MMC5 sees the
+// nametable fetches that start the frame, so they come from the main
+// background whatever the split says.
+func checkFirstScanline(t *testing.T, bus *Bus, columnColor func(column int) uint8) {
+	t.Helper()
+
+	frame := bus.Frame()
+	for x := 0; x < PPU_SCREEN_WIDTH; x++ {
+		expected := columnColor(x / 8)
+		if x < 16 {
+			expected = testColor2
+		}
+
+		if frame[0][x] != expected {
+			t.Fatalf("pixel (%d, 0) is color $%02X, want $%02X", x, frame[0][x], expected)
+		}
+	}
+}
+
+// A debugger peeking at the status registers must not acknowledge the IRQs
+// they report.
+func TestMMC5ReadOnlyStatusReadsKeepIRQsPending(t *testing.T) {
+	cart, err := LoadCartridgeBytes(testINESImage(5, 4, 2, 0, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapper := cart.mapper.(*mapper005)
+
+	mapper.IRQPending = true
+	mapper.PCMIRQPending = true
+	mapper.PCMIRQEnabled = true
+
+	for _, readOnly := range []bool{true, false} {
+		scanlineStatus, _ := cart.CPURead(0x5204, readOnly)
+		PCMStatus, _ := cart.CPURead(0x5010, readOnly)
+
+		if scanlineStatus&0x80 == 0 || PCMStatus&0x80 == 0 {
+			t.Errorf("readOnly %v: $5204 reads $%02X, $5010 reads $%02X, want bit 7 set", readOnly, scanlineStatus, PCMStatus)
+		}
+		if mapper.IRQPending != readOnly || mapper.PCMIRQPending != readOnly {
+			t.Errorf("readOnly %v: scanline IRQ pending %v, PCM IRQ pending %v after the read", readOnly, mapper.IRQPending, mapper.PCMIRQPending)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package components
+
+// mapper007 is AxROM: a switchable 32KB PRG bank, 8KB of CHR RAM, and bit 4
+// of the bank register picking which nametable fills the whole screen.
+// ANROM has no bus conflicts; AMROM and most AOROM boards do.
+type mapper007 struct {
+	PRGBanks32K     int
+	PRGAddressMask  uint16
+	CHRIsRAM        bool
+	hasBusConflicts bool
+	bankRegister    uint8
+}
+
+func init() {
+	RegisterMapper(7, newMapper007)
+}
+
+func newMapper007(header CartridgeHeader) Mapper {
+	mapper := &mapper007{
+		PRGBanks32K:     header.PRGROMSize / (2 * PRG_BANK_SIZE),
+		PRGAddressMask:  0x7FFF,
+		CHRIsRAM:        header.CHRROMSize == 0,
+		hasBusConflicts: header.Submapper == BUS_CONFLICTS_SUBMAPPER_AND,
+	}
+
+	// A 16KB image is mirrored into both halves of the window, as on NROM.
+	if mapper.PRGBanks32K == 0 {
+		mapper.PRGBanks32K = 1
+		mapper.PRGAddressMask = 0x3FFF
+	}
+
+	return mapper
+}
+
+func (this *mapper007) CPUMapRead(addr uint16, mappedAddr *uint32, data *uint8, readOnly bool) bool {
+	if addr >= 0x8000 {
+		bank := int(this.bankRegister&0x0F) % this.PRGBanks32K
+		*mappedAddr = uint32(bank*2*PRG_BANK_SIZE) | uint32(addr&this.PRGAddressMask)
+		return true
+	}
+
+	return false
+}
+
+func (this *mapper007) CPUMapWrite(addr uint16, mappedAddr *uint32, data uint8) bool {
+	if addr >= 0x8000 {
+		this.bankRegister = data
+	}
+
+	return false
+}
+
+func (this *mapper007) PPUMapRead(addr uint16, mappedAddr *uint32, data *uint8) bool {
+	if addr <= 0x1FFF {
+		*mappedAddr = uint32(addr)
+		return true
+	}
+
+	return false
+}
+
+func (this *mapper007) PPUMapWrite(addr uint16, mappedAddr *uint32, data uint8) bool {
+	if addr <= 0x1FFF && this.CHRIsRAM {
+		*mappedAddr = uint32(addr)
+		return true
+	}
+
+	return false
+}
+
+func (this *mapper007) HasBusConflicts() bool {
+	return this.hasBusConflicts
+}
+
+func (this *mapper007) Reset() {
+	this.bankRegister = 0
+}
+
+func (this *mapper007) Mirroring() Mirroring {
+	if this.bankRegister&0x10 != 0 {
+		return MIRROR_SINGLE_SCREEN_HIGH
+	}
+	return MIRROR_SINGLE_SCREEN_LOW
+}
+
+func (this *mapper007) IRQState() bool {
+	return false
+}
+
+func (this *mapper007) IRQClear() {
+
+}
+
+func (this *mapper007) Clock() {
+
+}
@@ -0,0 +1,41 @@
+package components
+
+import "testing"
+
+func TestAxROMSwitchesPRGAndNametable(t *testing.T) {
+	cart := testBankedCartridge(t, 7, 0, 8, 0, 0)
+
+	tests := []struct {
+		data      uint8
+		low, high uint8
+		mirroring Mirroring
+	}{
+		{0x02, 4, 5, MIRROR_SINGLE_SCREEN_LOW},
+		{0x13, 6, 7, MIRROR_SINGLE_SCREEN_HIGH},
+		{0x10, 0, 1, MIRROR_SINGLE_SCREEN_HIGH},
+	}
+
+	for _, test := range tests {
+		data := test.data
+		cart.CPUWrite(0x8000, &data)
+
+		low, high := readPRGBank(cart, 0x8000), readPRGBank(cart, 0xC000)
+		if low != test.low || high != test.high || cart.Mirroring() != test.mirroring {
+			t.Errorf("$%02X: banks %d and %d, mirroring %d; want %d and %d, mirroring %d",
+				test.data, low, high, cart.Mirroring(), test.low, test.high, test.mirroring)
+		}
+	}
+}
+
+func TestAxROM16KBIsMirrored(t *testing.T) {
+	cart, err := LoadCartridgeBytes(testINESImage(7, 1, 0, 0, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, addr := range []uint16{0x8001, 0xC001, 0xFFFF} {
+		if data, _ := cart.CPURead(addr, true); data != uint8(addr) {
+			t.Errorf("$%04X reads $%02X, want $%02X from the one 16KB bank", addr, data, uint8(addr))
+		}
+	}
+}
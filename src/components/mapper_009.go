@@ -0,0 +1,194 @@
+package components
+
+const (
+	MMC2_LATCH_FD = 0
+	MMC2_LATCH_FE = 1
+)
+
+// mapper009 is MMC2 (PxROM), and with isMMC4 set, MMC4 (FxROM, mapper 10).
+// Each 4KB pattern table has two CHR bank registers and a latch choosing
+// between them. The latch flips when the PPU fetches the high plane of
+// tile $FD or $FE from that table, so a game can switch banks mid-scanline
+// by placing those tiles in its nametables.
+//
+// MMC2 has one switchable 8KB PRG bank at $8000 and the last three fixed;
+// MMC4 has a switchable 16KB bank, the last one fixed and 8KB of PRG RAM.
+// For the first pattern table MMC2 only reacts to $0FD8 and $0FE8, while
+// MMC4 reacts to the whole $0FD8-$0FDF and $0FE8-$0FEF ranges as both chips
+// do for the second table.
+type mapper009 struct {
+	isMMC4     bool
+	PRGBanks8K int
+	CHRBanks4K int
+	PRGRAM     []uint8
+
+	PRGBank   uint8
+	CHRBanks  [2][2]uint8 // [pattern table][latch]: $B000-$E000
+	latches   [2]uint8    // 0 selects the $FD register, 1 the $FE one
+	mirroring Mirroring
+
+	// The latch changes after the fetch that triggers it, so the new bank
+	// is applied when the PPU drives its next address.
+	pendingLatch      int
+	pendingLatchValue uint8
+}
+
+func init() {
+	RegisterMapper(9, newMapper009)
+	RegisterMapper(10, newMapper010)
+}
+
+func newMapper009(header CartridgeHeader) Mapper {
+	mapper := &mapper009{
+		PRGBanks8K: header.PRGROMSize / PRG_BANK_SIZE_8K,
+		CHRBanks4K: (header.CHRROMSize + header.CHRRAMSize + header.CHRNVRAMSize) / (CHR_BANK_SIZE / 2),
+	}
+
+	if mapper.CHRBanks4K == 0 {
+		mapper.CHRBanks4K = 2
+	}
+
+	mapper.Reset()
+
+	return mapper
+}
+
+func newMapper010(header CartridgeHeader) Mapper {
+	mapper := newMapper009(header).(*mapper009)
+	mapper.isMMC4 = true
+
+	PRGRAMSize := header.BoardPRGRAMSize(PRG_RAM_BANK_SIZE)
+	if PRGRAMSize > 0 {
+		mapper.PRGRAM = make([]uint8, PRGRAMSize)
+	}
+
+	return mapper
+}
+
+func (this *mapper009) CPUMapRead(addr uint16, mappedAddr *uint32, data *uint8, readOnly bool) bool {
+	if addr >= 0x6000 && addr <= 0x7FFF && this.PRGRAM != nil {
+		*mappedAddr = MAPPER_HANDLED
+		*data = this.PRGRAM[int(addr&0x1FFF)%len(this.PRGRAM)]
+		return true
+	}
+
+	if addr >= 0x8000 {
+		*mappedAddr = this.PRGOffset(addr)
+		return true
+	}
+
+	return false
+}
+
+func (this *mapper009) CPUMapWrite(addr uint16, mappedAddr *uint32, data uint8) bool {
+	if addr >= 0x6000 && addr <= 0x7FFF && this.PRGRAM != nil {
+		*mappedAddr = MAPPER_HANDLED
+		this.PRGRAM[int(addr&0x1FFF)%len(this.PRGRAM)] = data
+		return true
+	}
+
+	switch addr & 0xF000 {
+	case 0xA000:
+		this.PRGBank = data & 0x0F
+	case 0xB000:
+		this.CHRBanks[0][MMC2_LATCH_FD] = data & 0x1F
+	case 0xC000:
+		this.CHRBanks[0][MMC2_LATCH_FE] = data & 0x1F
+	case 0xD000:
+		this.CHRBanks[1][MMC2_LATCH_FD] = data & 0x1F
+	case 0xE000:
+		this.CHRBanks[1][MMC2_LATCH_FE] = data & 0x1F
+	case 0xF000:
+		if data&0x01 == 0 {
+			this.mirroring = MIRROR_VERTICAL
+		} else {
+			this.mirroring = MIRROR_HORIZONTAL
+		}
+	}
+
+	return false
+}
+
+func (this *mapper009) PRGOffset(addr uint16) uint32 {
+	if this.isMMC4 {
+		banks16K := this.PRGBanks8K / 2
+		bank := banks16K - 1
+		if addr < 0xC000 {
+			bank = int(this.PRGBank) % banks16K
+		}
+		return uint32(bank*PRG_BANK_SIZE) | uint32(addr&0x3FFF)
+	}
+
+	bank := this.PRGBanks8K - 4 + int(addr>>13)&0x03
+	if addr < 0xA000 {
+		bank = int(this.PRGBank) % this.PRGBanks8K
+	}
+	return uint32(bank*PRG_BANK_SIZE_8K) | uint32(addr&0x1FFF)
+}
+
+func (this *mapper009) PPUMapRead(addr uint16, mappedAddr *uint32, data *uint8) bool {
+	if addr > 0x1FFF {
+		return false
+	}
+
+	table := addr >> 12
+	bank := int(this.CHRBanks[table][this.latches[table]]) % this.CHRBanks4K
+
+	*mappedAddr = uint32(bank*CHR_BANK_SIZE/2) | uint32(addr&0x0FFF)
+	return true
+}
+
+func (this *mapper009) PPUMapWrite(addr uint16, mappedAddr *uint32, data uint8) bool {
+	return false
+}
+
+// PPUAddress applies the latch change of the previous fetch, then checks
+// whether this fetch triggers one.
+func (this *mapper009) PPUAddress(addr uint16) {
+	if this.pendingLatch >= 0 {
+		this.latches[this.pendingLatch] = this.pendingLatchValue
+		this.pendingLatch = -1
+	}
+
+	if addr > 0x1FFF {
+		return
+	}
+
+	table := int(addr >> 12)
+	tileRow := addr & 0x0FF8
+
+	if table == 0 && !this.isMMC4 && addr&0x0007 != 0 {
+		return
+	}
+
+	switch tileRow {
+	case 0x0FD8:
+		this.pendingLatch, this.pendingLatchValue = table, MMC2_LATCH_FD
+	case 0x0FE8:
+		this.pendingLatch, this.pendingLatchValue = table, MMC2_LATCH_FE
+	}
+}
+
+func (this *mapper009) Reset() {
+	this.PRGBank = 0
+	this.CHRBanks = [2][2]uint8{}
+	this.latches = [2]uint8{MMC2_LATCH_FE, MMC2_LATCH_FE}
+	this.mirroring = MIRROR_VERTICAL
+	this.pendingLatch = -1
+}
+
+func (this *mapper009) Mirroring() Mirroring {
+	return this.mirroring
+}
+
+func (this *mapper009) IRQState() bool {
+	return false
+}
+
+func (this *mapper009) IRQClear() {
+
+}
+
+func (this *mapper009) Clock() {
+
+}
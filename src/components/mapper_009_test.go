@@ -0,0 +1,58 @@
+package components
+
+import "testing"
+
+// Every nametable row holds a $FD tile in column 10 and a $FE tile in column
+// 21; the $FD banks are solid color 1 and the $FE banks solid color 2. A
+// latch only flips after the triggering tile is fetched, so each row should
+// read: columns 0-10 in color 2, 11-21 in color 1, 22-31 in color 2.
+const (
+	latchFDColumn = 10
+	latchFEColumn = 21
+)
+
+// testLatchFrame renders a frame with the latch-switching nametable on
+// mapper 9 (MMC2) or 10 (MMC4). The background comes from the second
+// pattern table, whose latch reacts to every row of the trigger tiles on
+// both chips.
+func testLatchFrame(t *testing.T, mapperID uint8) {
+	image := testINESImage(mapperID, 4, 2, 0, nil)
+	fillAlternatingCHR(image)
+
+	bus := newTestBus(t, image)
+
+	// Both pattern tables: banks 1/3 (color 1) when the latch is $FD, 0/2
+	// (color 2) when $FE.
+	bus.CPUWrite(0xB000, 1)
+	bus.CPUWrite(0xC000, 0)
+	bus.CPUWrite(0xD000, 3)
+	bus.CPUWrite(0xE000, 2)
+
+	nameTable := make([]uint8, 32*30+64)
+	for row := 0; row < 30; row++ {
+		nameTable[row*32+latchFDColumn] = 0xFD
+		nameTable[row*32+latchFEColumn] = 0xFE
+	}
+	writeVRAM(bus, 0x2000, nameTable...)
+	writeTestPalette(bus)
+	startRendering(bus, PPU_CTRL_BACKGROUND_TABLE)
+
+	// The first frame starts wherever reset left the PPU; check the second.
+	bus.RunFrame()
+	bus.RunFrame()
+
+	checkColumns(t, bus, 0, func(column int) uint8 {
+		if column > latchFDColumn && column <= latchFEColumn {
+			return testColor1
+		}
+		return testColor2
+	})
+}
+
+func TestMMC2Latch(t *testing.T) {
+	testLatchFrame(t, 9)
+}
+
+func TestMMC4Latch(t *testing.T) {
+	testLatchFrame(t, 10)
+}
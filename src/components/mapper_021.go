@@ -0,0 +1,387 @@
+package components
+
+const (
+	// VRC4_PRESCALER_PERIOD is the scanline length in PPU dots. In scanline
+	// mode the prescaler counts 3 dots per CPU cycle down from it and clocks
+	// the IRQ counter each time it wraps.
+	VRC4_PRESCALER_PERIOD = 341
+
+	VRC4_IRQ_ENABLE_AFTER_ACK = 0x01
+	VRC4_IRQ_ENABLE           = 0x02
+	VRC4_IRQ_CYCLE_MODE       = 0x04
+)
+
+// vrcWiring is how a board connects CPU address lines to the two register
+// select pins of the chip. Boards without a submapper get the OR of every
+// wiring sharing their mapper number, which works because each game only
+// writes through its own lines.
+type vrcWiring struct {
+	selectLow  uint16 // address lines wired to the chip's A0
+	selectHigh uint16 // address lines wired to the chip's A1
+	isVRC2     bool
+	CHRShift   uint // VRC2a leaves the low CHR bank bit unconnected
+}
+
+var vrcWirings = map[uint16]map[uint8]vrcWiring{
+	21: {
+		0: {selectLow: 0x0042, selectHigh: 0x0084}, // VRC4a or VRC4c
+		1: {selectLow: 0x0002, selectHigh: 0x0004}, // VRC4a: A1, A2
+		2: {selectLow: 0x0040, selectHigh: 0x0080}, // VRC4c: A6, A7
+	},
+	22: {
+		0: {selectLow: 0x0002, selectHigh: 0x0001, isVRC2: true, CHRShift: 1}, // VRC2a: A1, A0
+	},
+	23: {
+		0: {selectLow: 0x0005, selectHigh: 0x000A},               // VRC4f, VRC4e or VRC2b
+		1: {selectLow: 0x0001, selectHigh: 0x0002},               // VRC4f: A0, A1
+		2: {selectLow: 0x0004, selectHigh: 0x0008},               // VRC4e: A2, A3
+		3: {selectLow: 0x0001, selectHigh: 0x0002, isVRC2: true}, // VRC2b: A0, A1
+	},
+	25: {
+		0: {selectLow: 0x000A, selectHigh: 0x0005},               // VRC4b, VRC4d or VRC2c
+		1: {selectLow: 0x0002, selectHigh: 0x0001},               // VRC4b: A1, A0
+		2: {selectLow: 0x0008, selectHigh: 0x0004},               // VRC4d: A3, A2
+		3: {selectLow: 0x0002, selectHigh: 0x0001, isVRC2: true}, // VRC2c: A1, A0
+	},
+}
+
+// mapper021 is the Konami VRC2 and VRC4 family behind mappers 21, 22, 23
+// and 25. Two 8KB PRG banks are switchable, the other two fixed to the end
+// of the ROM; eight 1KB CHR banks are each set through a low and a high
+// nibble register. The boards differ in which address lines select the
+// register inside each $1000 block.
+//
+// VRC4 adds single-screen mirroring, a PRG swap mode and an IRQ counter
+// clocked by the CPU, either directly or through a prescaler that divides
+// it down to scanlines. VRC2 instead has a one-bit microwire latch at
+// $6000-$6FFF, used by some games as an EEPROM interface and by others as
+// a copy-protection check, when the board has no PRG RAM.
+type mapper021 struct {
+	PRGBanks8K int
+	CHRBanks1K int
+	CHRIsRAM   bool
+	PRGRAM     []uint8
+	wiring     vrcWiring
+
+	PRGBanks   [2]uint8
+	PRGSwapped bool
+	CHRBanks   [8]uint16
+	mirroring  Mirroring
+
+	microwireLatch uint8
+
+	IRQLatch     uint8
+	IRQCounter   uint8
+	IRQControl   uint8
+	IRQPrescaler int
+	IRQActive    bool
+}
+
+func init() {
+	RegisterMapper(21, newMapper021)
+	RegisterMapper(22, newMapper021)
+	RegisterMapper(23, newMapper021)
+	RegisterMapper(25, newMapper021)
+}
+
+func newMapper021(header CartridgeHeader) Mapper {
+	wiring, ok := vrcWirings[header.Mapper][header.Submapper]
+	if !ok {
+		wiring = vrcWirings[header.Mapper][0]
+	}
+
+	mapper := &mapper021{
+		PRGBanks8K: header.PRGROMSize / PRG_BANK_SIZE_8K,
+		CHRBanks1K: (header.CHRROMSize + header.CHRRAMSize + header.CHRNVRAMSize) / 1024,
+		CHRIsRAM:   header.CHRROMSize == 0,
+		wiring:     wiring,
+	}
+
+	if mapper.CHRBanks1K == 0 {
+		mapper.CHRBanks1K = CHR_BANK_SIZE / 1024
+	}
+
+	// Only a few VRC4 games have PRG RAM, and RAM would hide the VRC2
+	// microwire latch.
+	PRGRAMSize := header.BoardPRGRAMSize(0)
+
+	if PRGRAMSize > 0 {
+		mapper.PRGRAM = make([]uint8, PRGRAMSize)
+	}
+
+	mapper.Reset()
+
+	return mapper
+}
+
+// registerSelect folds a CPU address into the $x000-$x003 register it
+// reaches on this board.
+func (this *mapper021) registerSelect(addr uint16) uint16 {
+	register := addr & 0xF000
+	if addr&this.wiring.selectLow != 0 {
+		register |= 0x0001
+	}
+	if addr&this.wiring.selectHigh != 0 {
+		register |= 0x0002
+	}
+	return register
+}
+
+func (this *mapper021) CPUMapRead(addr uint16, mappedAddr *uint32, data *uint8, readOnly bool) bool {
+	if addr >= 0x6000 && addr <= 0x7FFF {
+		if this.PRGRAM != nil {
+			*mappedAddr = MAPPER_HANDLED
+			*data = this.PRGRAM[int(addr&0x1FFF)%len(this.PRGRAM)]
+			return true
+		}
+
+		if this.isMicrowireLatch(addr) {
+			*mappedAddr = MAPPER_HANDLED
+			*data = this.microwireLatch
+			return true
+		}
+
+		return false
+	}
+
+	if addr >= 0x8000 {
+		*mappedAddr = this.PRGOffset(addr)
+		return true
+	}
+
+	return false
+}
+
+// isMicrowireLatch reports whether addr reaches the VRC2 latch, which
+// answers at $6000-$6FFF on boards without PRG RAM.
+func (this *mapper021) isMicrowireLatch(addr uint16) bool {
+	return this.wiring.isVRC2 && this.PRGRAM == nil && addr >= 0x6000 && addr <= 0x6FFF
+}
+
+// DrivenBits reports that the microwire latch only drives D0.
+func (this *mapper021) DrivenBits(addr uint16) uint8 {
+	if this.isMicrowireLatch(addr) {
+		return 0x01
+	}
+	return 0xFF
+}
+
+func (this *mapper021) CPUMapWrite(addr uint16, mappedAddr *uint32, data uint8) bool {
+	if addr >= 0x6000 && addr <= 0x7FFF {
+		if this.PRGRAM != nil {
+			*mappedAddr = MAPPER_HANDLED
+			this.PRGRAM[int(addr&0x1FFF)%len(this.PRGRAM)] = data
+			return true
+		}
+
+		if this.isMicrowireLatch(addr) {
+			this.microwireLatch = data & 0x01
+		}
+
+		return false
+	}
+
+	if addr < 0x8000 {
+		return false
+	}
+
+	register := this.registerSelect(addr)
+
+	// VRC2 has 4-bit PRG registers, VRC4 5-bit ones.
+	PRGBankMask := uint8(0x1F)
+	if this.wiring.isVRC2 {
+		PRGBankMask = 0x0F
+	}
+
+	switch {
+	case register <= 0x8003:
+		this.PRGBanks[0] = data & PRGBankMask
+	case register <= 0x9003:
+		this.writeControl(register, data)
+	case register <= 0xAFFF:
+		this.PRGBanks[1] = data & PRGBankMask
+	case register <= 0xEFFF:
+		this.writeCHRBank(register, data)
+	case !this.wiring.isVRC2:
+		this.writeIRQ(register, data)
+	}
+
+	return false
+}
+
+// writeControl handles $9000-$9003. VRC2 only has one mirroring bit there;
+// VRC4 has four mirroring modes at $9000 and the PRG swap mode at $9002.
+// The WRAM enable in $9002 bit 0 is not enforced, as several games never
+// set it.
+func (this *mapper021) writeControl(register uint16, data uint8) {
+	if this.wiring.isVRC2 {
+		if data&0x01 == 0 {
+			this.mirroring = MIRROR_VERTICAL
+		} else {
+			this.mirroring = MIRROR_HORIZONTAL
+		}
+		return
+	}
+
+	switch register {
+	case 0x9000:
+		switch data & 0x03 {
+		case 0:
+			this.mirroring = MIRROR_VERTICAL
+		case 1:
+			this.mirroring = MIRROR_HORIZONTAL
+		case 2:
+			this.mirroring = MIRROR_SINGLE_SCREEN_LOW
+		case 3:
+			this.mirroring = MIRROR_SINGLE_SCREEN_HIGH
+		}
+	case 0x9002:
+		this.PRGSwapped = data&0x02 != 0
+	}
+}
+
+// writeCHRBank handles $B000-$E003: each $1000 block holds two banks, the
+// low nibble at $x000/$x002 and the high one at $x001/$x003. The high part
+// is 4 bits on VRC2 and 5 on VRC4.
+func (this *mapper021) writeCHRBank(register uint16, data uint8) {
+	bank := int(register-0xB000)>>11 | int(register>>1)&0x01
+	CHRBank := &this.CHRBanks[bank]
+
+	highMask := uint8(0x1F)
+	if this.wiring.isVRC2 {
+		highMask = 0x0F
+	}
+
+	if register&0x0001 == 0 {
+		*CHRBank = *CHRBank&0x1F0 | uint16(data&0x0F)
+	} else {
+		*CHRBank = *CHRBank&0x00F | uint16(data&highMask)<<4
+	}
+}
+
+// writeIRQ handles the VRC4 IRQ registers at $F000-$F003.
+func (this *mapper021) writeIRQ(register uint16, data uint8) {
+	switch register {
+	case 0xF000:
+		this.IRQLatch = this.IRQLatch&0xF0 | data&0x0F
+	case 0xF001:
+		this.IRQLatch = this.IRQLatch&0x0F | data<<4
+	case 0xF002:
+		this.IRQControl = data & 0x07
+		if this.IRQControl&VRC4_IRQ_ENABLE != 0 {
+			this.IRQCounter = this.IRQLatch
+			this.IRQPrescaler = VRC4_PRESCALER_PERIOD
+		}
+		this.IRQActive = false
+	case 0xF003:
+		if this.IRQControl&VRC4_IRQ_ENABLE_AFTER_ACK != 0 {
+			this.IRQControl |= VRC4_IRQ_ENABLE
+		} else {
+			this.IRQControl &^= VRC4_IRQ_ENABLE
+		}
+		this.IRQActive = false
+	}
+}
+
+// PRGOffset places the first PRG register at $8000 and the second to last
+// bank at $C000, or the other way around in swap mode. The second register
+// always sits at $A000 and the last bank at $E000.
+func (this *mapper021) PRGOffset(addr uint16) uint32 {
+	secondLast := this.PRGBanks8K - 2
+	last := this.PRGBanks8K - 1
+
+	var bank int
+	switch (addr >> 13) & 0x03 {
+	case 0:
+		bank = int(this.PRGBanks[0])
+		if this.PRGSwapped {
+			bank = secondLast
+		}
+	case 1:
+		bank = int(this.PRGBanks[1])
+	case 2:
+		bank = secondLast
+		if this.PRGSwapped {
+			bank = int(this.PRGBanks[0])
+		}
+	case 3:
+		bank = last
+	}
+
+	bank %= this.PRGBanks8K
+
+	return uint32(bank*PRG_BANK_SIZE_8K) | uint32(addr&0x1FFF)
+}
+
+func (this *mapper021) CHROffset(addr uint16) uint32 {
+	bank := int(this.CHRBanks[addr>>10]>>this.wiring.CHRShift) % this.CHRBanks1K
+	return uint32(bank*1024) | uint32(addr&0x03FF)
+}
+
+func (this *mapper021) PPUMapRead(addr uint16, mappedAddr *uint32, data *uint8) bool {
+	if addr <= 0x1FFF {
+		*mappedAddr = this.CHROffset(addr)
+		return true
+	}
+
+	return false
+}
+
+func (this *mapper021) PPUMapWrite(addr uint16, mappedAddr *uint32, data uint8) bool {
+	if addr <= 0x1FFF && this.CHRIsRAM {
+		*mappedAddr = this.CHROffset(addr)
+		return true
+	}
+
+	return false
+}
+
+func (this *mapper021) Reset() {
+	this.PRGBanks = [2]uint8{0, 1}
+	this.PRGSwapped = false
+	this.CHRBanks = [8]uint16{}
+	this.mirroring = MIRROR_VERTICAL
+	this.microwireLatch = 0
+
+	this.IRQLatch = 0
+	this.IRQCounter = 0
+	this.IRQControl = 0
+	this.IRQPrescaler = VRC4_PRESCALER_PERIOD
+	this.IRQActive = false
+}
+
+func (this *mapper021) Mirroring() Mirroring {
+	return this.mirroring
+}
+
+func (this *mapper021) IRQState() bool {
+	return this.IRQActive
+}
+
+func (this *mapper021) IRQClear() {
+	this.IRQActive = false
+}
+
+// Clock runs the VRC4 IRQ counter. In cycle mode it counts every CPU cycle;
+// in scanline mode only when the prescaler wraps, which happens every 113
+// or 114 cycles to average one scanline.
+func (this *mapper021) Clock() {
+	if this.wiring.isVRC2 || this.IRQControl&VRC4_IRQ_ENABLE == 0 {
+		return
+	}
+
+	if this.IRQControl&VRC4_IRQ_CYCLE_MODE == 0 {
+		this.IRQPrescaler -= 3
+		if this.IRQPrescaler > 0 {
+			return
+		}
+		this.IRQPrescaler += VRC4_PRESCALER_PERIOD
+	}
+
+	if this.IRQCounter == 0xFF {
+		this.IRQCounter = this.IRQLatch
+		this.IRQActive = true
+	} else {
+		this.IRQCounter++
+	}
+}
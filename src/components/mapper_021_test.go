@@ -0,0 +1,180 @@
+package components
+
+import "testing"
+
+func TestVRC2MicrowireLatchOnINES(t *testing.T) {
+	bus := newTestBus(t, testINESImage(22, 2, 1, 0, nil))
+
+	for _, bit := range []uint8{1, 0} {
+		bus.CPUWrite(0x6000, bit)
+
+		// The latch only drives D0; the other lines keep what the last
+		// read left on the bus.
+		bus.CPURead(0x0000, false)
+		lastBus := bus.openBus
+
+		if data := bus.CPURead(0x6123, false); data != lastBus&0xFE|bit {
+			t.Errorf("after writing %d: $6123 reads $%02X, want $%02X", bit, data, lastBus&0xFE|bit)
+		}
+	}
+}
+
+func TestVRCBatteryINESHasPRGRAM(t *testing.T) {
+	cart, err := LoadCartridgeBytes(testINESImage(22, 2, 1, INES_FLAG_BATTERY, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := uint8(0xA5)
+	cart.CPUWrite(0x6123, &data)
+
+	if data, driven := cart.CPURead(0x6123, true); !driven || data != 0xA5 {
+		t.Errorf("$6123 reads $%02X (driven %v), want $A5", data, driven)
+	}
+}
+
+// vrcTestWiring is which CPU address lines reach the chip's register select
+// pins on one board.
+type vrcTestWiring struct {
+	board     string
+	mapperID  uint8
+	submapper uint8
+	low, high uint16
+}
+
+// Boards without a submapper must accept the lines of each board sharing
+// their mapper number.
+var vrcTestWirings = []vrcTestWiring{
+	{"VRC4a", 21, 1, 0x0002, 0x0004},
+	{"VRC4c", 21, 2, 0x0040, 0x0080},
+	{"VRC4a as 21", 21, 0, 0x0002, 0x0004},
+	{"VRC4c as 21", 21, 0, 0x0040, 0x0080},
+	{"VRC2a", 22, 0, 0x0002, 0x0001},
+	{"VRC4f", 23, 1, 0x0001, 0x0002},
+	{"VRC4e", 23, 2, 0x0004, 0x0008},
+	{"VRC2b", 23, 3, 0x0001, 0x0002},
+	{"VRC4f as 23", 23, 0, 0x0001, 0x0002},
+	{"VRC4e as 23", 23, 0, 0x0004, 0x0008},
+	{"VRC4b", 25, 1, 0x0002, 0x0001},
+	{"VRC4d", 25, 2, 0x0008, 0x0004},
+	{"VRC2c", 25, 3, 0x0002, 0x0001},
+	{"VRC4b as 25", 25, 0, 0x0002, 0x0001},
+	{"VRC4d as 25", 25, 0, 0x0008, 0x0004},
+}
+
+func TestVRCSubmapperWiring(t *testing.T) {
+	for _, wiring := range vrcTestWirings {
+		cart := testBankedCartridge(t, wiring.mapperID, wiring.submapper, 2, 1, 0)
+		write := func(addr uint16, data uint8) { cart.CPUWrite(addr, &data) }
+
+		// $B000-$B003: CHR bank 0 low and high nibble, then bank 1.
+		write(0xB000, 0x03)
+		write(0xB000|wiring.low, 0x02)
+		write(0xB000|wiring.high, 0x05)
+		write(0xB000|wiring.high|wiring.low, 0x01)
+
+		mapper := cart.mapper.(*mapper021)
+		if mapper.CHRBanks[0] != 0x23 || mapper.CHRBanks[1] != 0x15 {
+			t.Errorf("%s: CHR banks $%02X and $%02X, want $23 and $15", wiring.board, mapper.CHRBanks[0], mapper.CHRBanks[1])
+		}
+	}
+}
+
+func TestVRCRegisterWidths(t *testing.T) {
+	tests := []struct {
+		board     string
+		mapperID  uint8
+		submapper uint8
+		PRGBank   uint8
+		CHRBank   uint16
+	}{
+		{"VRC2b", 23, 3, 0x0F, 0x0FF},
+		{"VRC4f", 23, 1, 0x1F, 0x1FF},
+	}
+
+	for _, test := range tests {
+		cart := testBankedCartridge(t, test.mapperID, test.submapper, 2, 1, 0)
+		write := func(addr uint16, data uint8) { cart.CPUWrite(addr, &data) }
+
+		write(0x8000, 0xFF)
+		write(0xB000, 0xFF)
+		write(0xB001, 0xFF)
+
+		mapper := cart.mapper.(*mapper021)
+		if mapper.PRGBanks[0] != test.PRGBank || mapper.CHRBanks[0] != test.CHRBank {
+			t.Errorf("%s: PRG bank $%02X and CHR bank $%03X after writing $FF, want $%02X and $%03X",
+				test.board, mapper.PRGBanks[0], mapper.CHRBanks[0], test.PRGBank, test.CHRBank)
+		}
+	}
+}
+
+// testVRC4IRQ starts the IRQ counter of a VRC4f board from latch in the
+// given mode and returns the CPU cycles until it fires, or -1.
+func testVRC4IRQ(t *testing.T, latch uint8, control uint8) (*Cartridge, int) {
+	cart := testBankedCartridge(t, 23, 1, 2, 1, 0)
+	write := func(addr uint16, data uint8) { cart.CPUWrite(addr, &data) }
+
+	write(0xF000, latch&0x0F)
+	write(0xF001, latch>>4)
+	write(0xF002, control)
+
+	// The longest count is 256 scanlines.
+	return cart, cyclesUntilIRQ(cart, 256*VRC4_PRESCALER_PERIOD/3+1)
+}
+
+func cyclesUntilIRQ(cart *Cartridge, limit int) int {
+	for cycles := 1; cycles <= limit; cycles++ {
+		cart.Clock()
+		if cart.IRQState() {
+			return cycles
+		}
+	}
+	return -1
+}
+
+// In cycle mode the counter steps every CPU cycle and fires on the step
+// past $FF, reloading from the latch.
+func TestVRC4IRQCycleMode(t *testing.T) {
+	for _, latch := range []uint8{0xF0, 0xFF, 0x80} {
+		cart, cycles := testVRC4IRQ(t, latch, VRC4_IRQ_ENABLE|VRC4_IRQ_CYCLE_MODE|VRC4_IRQ_ENABLE_AFTER_ACK)
+
+		if expected := 256 - int(latch); cycles != expected {
+			t.Errorf("latch $%02X: IRQ after %d cycles, want %d", latch, cycles, expected)
+		}
+
+		// Acknowledging keeps the counter running from the reloaded latch.
+		ack := uint8(0)
+		cart.CPUWrite(0xF003, &ack)
+		if cycles := cyclesUntilIRQ(cart, 1000); cycles != 256-int(latch) {
+			t.Errorf("latch $%02X: second IRQ after %d cycles, want %d", latch, cycles, 256-int(latch))
+		}
+	}
+}
+
+// In scanline mode the prescaler steps the counter every 341 dots, 113 or
+// 114 CPU cycles, so n scanlines take n*341/3 cycles rounded up.
+func TestVRC4IRQScanlineMode(t *testing.T) {
+	for _, latch := range []uint8{0xFF, 0xFE, 0xFD, 0xF0} {
+		_, cycles := testVRC4IRQ(t, latch, VRC4_IRQ_ENABLE)
+
+		scanlines := 256 - int(latch)
+		if expected := (scanlines*VRC4_PRESCALER_PERIOD + 2) / 3; cycles != expected {
+			t.Errorf("latch $%02X: IRQ after %d cycles, want %d for %d scanlines", latch, cycles, expected, scanlines)
+		}
+	}
+}
+
+// Without the enable-after-acknowledge bit, $F003 stops the counter.
+func TestVRC4IRQAcknowledgeDisables(t *testing.T) {
+	cart, _ := testVRC4IRQ(t, 0xFF, VRC4_IRQ_ENABLE|VRC4_IRQ_CYCLE_MODE)
+
+	ack := uint8(0)
+	cart.CPUWrite(0xF003, &ack)
+
+	if cart.IRQState() {
+		t.Errorf("IRQ still asserted after $F003")
+	}
+	if cycles := cyclesUntilIRQ(cart, 1000); cycles != -1 {
+		t.Errorf("IRQ after %d more cycles with the counter disabled", cycles)
+	}
+}
@@ -0,0 +1,175 @@
+package components
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// nestest.nes runs its whole CPU test suite without a PPU when execution
+// starts at $C000 instead of the reset vector ("automation mode"). The
+// reference log was recorded with Nintendulator, one line per instruction.
+// Neither file is distributed with the repository.
+const (
+	nestestROMPath        = "testdata/nestest.nes"
+	nestestLogPath        = "testdata/nestest.log"
+	nestestAutomationAddr = 0xC000
+)
+
+var nestestRegistersPattern = regexp.MustCompile(`A:([0-9A-F]{2}) X:([0-9A-F]{2}) Y:([0-9A-F]{2}) P:([0-9A-F]{2}) SP:([0-9A-F]{2}) PPU:\s*(-?\d+),\s*(\d+) CYC:(\d+)`)
+
+type nestestField struct {
+	name  string
+	value string
+}
+
+// TestNestest executes nestest.nes on a full Bus and compares every
+// instruction, PPU position included, against the golden log. It stops at
+// the first line that diverges.
+func TestNestest(t *testing.T) {
+	cartridge, err := LoadCartridge(nestestROMPath)
+	if errors.Is(err, os.ErrNotExist) {
+		t.Skipf("%s not found", nestestROMPath)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logFile, err := os.Open(nestestLogPath)
+	if errors.Is(err, os.ErrNotExist) {
+		t.Skipf("%s not found", nestestLogPath)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer logFile.Close()
+
+	bus := NewBus()
+	bus.InsertCartridge(cartridge)
+	bus.Reset()
+
+	stepNestestInstruction(bus)
+	bus.cpu.programCounterReg = nestestAutomationAddr
+
+	scanner := bufio.NewScanner(logFile)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		expected := strings.TrimRight(scanner.Text(), "\r")
+		if expected == "" {
+			continue
+		}
+
+		actual := nestestTraceLine(bus)
+
+		if fieldsDiffs := diffNestestLines(expected, actual); len(fieldsDiffs) > 0 {
+			t.Fatalf("nestest diverged at line %d\nexpected: %s\nactual:   %s\n%s",
+				lineNumber, expected, actual, strings.Join(fieldsDiffs, "\n"))
+		}
+
+		stepNestestInstruction(bus)
+	}
+
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// stepNestestInstruction clocks the console through one instruction and
+// stops right before the tick that starts the next one. The PPU has then
+// run the dots of every CPU cycle so far, as in the log.
+func stepNestestInstruction(bus *Bus) {
+	startCycle := bus.cpu.clockCount
+
+	for {
+		isCPUTickNext := bus.cpuClockTick+bus.timing.ppuClockDivider >= bus.timing.cpuClockDivider
+		if isCPUTickNext && bus.cpu.clockCount > startCycle && bus.cpu.InstructionComplete() {
+			return
+		}
+		bus.Clock()
+	}
+}
+
+// nestestTraceLine formats the CPU state at an instruction boundary in the
+// nestest.log layout, with the PPU position read from the PPU itself.
+func nestestTraceLine(bus *Bus) string {
+	cpu := bus.cpu
+	instructionBytes, text := cpu.DisassembleInstruction(cpu.programCounterReg)
+
+	hexBytes := make([]string, len(instructionBytes))
+	for i, instructionByte := range instructionBytes {
+		hexBytes[i] = fmt.Sprintf("%02X", instructionByte)
+	}
+
+	unofficialMarker := ' '
+	if lookup[instructionBytes[0]].unofficial {
+		unofficialMarker = '*'
+	}
+
+	return fmt.Sprintf("%04X  %-8s %c%-32sA:%02X X:%02X Y:%02X P:%02X SP:%02X PPU:%3d,%3d CYC:%d",
+		cpu.programCounterReg, strings.Join(hexBytes, " "), unofficialMarker, text,
+		cpu.accumulatorReg, cpu.xReg, cpu.yReg, uint8(cpu.statusReg), cpu.stackPointerReg,
+		bus.ppu.scanline, bus.ppu.dot, cpu.clockCount)
+}
+
+func parseNestestLine(line string) []nestestField {
+	for len(line) < 48 {
+		line += " "
+	}
+
+	fields := []nestestField{
+		{"PC", line[0:4]},
+		{"bytes", strings.TrimSpace(line[6:15])},
+		// The log names ISC after its other common mnemonic, ISB.
+		{"disassembly", strings.Replace(strings.TrimSpace(line[15:48]), "ISB", "ISC", 1)},
+	}
+
+	registers := nestestRegistersPattern.FindStringSubmatch(line[48:])
+	if registers == nil {
+		return append(fields, nestestField{"registers", strings.TrimSpace(line[48:])})
+	}
+
+	// Some logs number the pre-render scanline -1 rather than 261.
+	scanline, _ := strconv.Atoi(registers[6])
+	if scanline < 0 {
+		scanline += regionTimings[REGION_NTSC].scanlinesPerFrame
+	}
+	dot, _ := strconv.Atoi(registers[7])
+
+	return append(fields,
+		nestestField{"A", registers[1]},
+		nestestField{"X", registers[2]},
+		nestestField{"Y", registers[3]},
+		nestestField{"P", registers[4]},
+		nestestField{"SP", registers[5]},
+		nestestField{"PPU", fmt.Sprintf("%d,%d", scanline, dot)},
+		nestestField{"CYC", registers[8]},
+	)
+}
+
+func diffNestestLines(expected string, actual string) []string {
+	expectedFields := parseNestestLine(expected)
+	actualFields := parseNestestLine(actual)
+
+	var fieldsDiffs []string
+
+	for i, expectedField := range expectedFields {
+		if i >= len(actualFields) {
+			fieldsDiffs = append(fieldsDiffs, fmt.Sprintf("%s: expected %q, missing", expectedField.name, expectedField.value))
+			continue
+		}
+
+		actualField := actualFields[i]
+		if expectedField.name != actualField.name || expectedField.value != actualField.value {
+			fieldsDiffs = append(fieldsDiffs, fmt.Sprintf("%s: expected %q, got %q", expectedField.name, expectedField.value, actualField.value))
+		}
+	}
+
+	return fieldsDiffs
+}
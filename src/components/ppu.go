@@ -1,35 +1,97 @@
 package components
 
+const (
+	PPU_DOTS_PER_SCANLINE      = 341
+	PPU_OAM_SIZE               = 256
+	PPU_CTRL_NMI_ENABLE        = 0x80
+	PPU_MASK_RENDERING_ENABLED = 0x18
+	PPU_STATUS_VBLANK          = 0x80
+	PPU_CTRL_INCREMENT_32      = 0x04
+)
+
 type PPU struct {
 	cartridge         *Cartridge
 	vram_nameTable    [2][1024]uint8
 	vram_paletteTable [32]uint8
 	vram_patternTable [2][4096]uint8 // for future implementation
+
+	// ioLatch is the PPU's own data bus. Writes to any register charge it and
+	// reads of write-only registers, or of the unused bits of $2002, return
+	// what is left on it.
+	ioLatch uint8
+
+	oam        [PPU_OAM_SIZE]uint8
+	oamAddress uint8
+
+	control uint8
+	mask    uint8
+	status  uint8
+
+	timing        regionTiming
+	scanline      int
+	dot           int
+	oddFrame      bool
+	frameComplete bool
+
+	// Loopy's scroll registers: the current VRAM address, the temporary
+	// one $2005/$2006 build up, fine X scroll and the shared write toggle.
+	vramAddress uint16
+	tempAddress uint16
+	fineX       uint8
+	writeToggle bool
+	readBuffer  uint8
+	background  backgroundPipeline
+	frame       [PPU_SCREEN_HEIGHT][PPU_SCREEN_WIDTH]uint8
+
+	// nmi is raised when the NMI output goes low and is cleared by the bus
+	// once it has been forwarded to the CPU.
+	nmi bool
 }
 
 func (this *PPU) CPUWrite(addr uint16, data *uint8) {
+	this.ioLatch = *data
+
 	switch addr {
 	case 0x0000:
-		break
+		// Enabling NMI during vblank fires it right away.
+		if this.control&PPU_CTRL_NMI_ENABLE == 0 && *data&PPU_CTRL_NMI_ENABLE != 0 && this.status&PPU_STATUS_VBLANK != 0 {
+			this.nmi = true
+		}
+		this.control = *data
+		this.tempAddress = this.tempAddress&0xF3FF | uint16(*data&0x03)<<10
 	case 0x0001:
-		break
+		this.mask = *data
 	case 0x0002:
 		break
 	case 0x0003:
-		break
+		this.oamAddress = *data
 	case 0x0004:
-		break
+		this.oam[this.oamAddress] = *data
+		this.oamAddress++
 	case 0x0005:
-		break
+		if !this.writeToggle {
+			this.tempAddress = this.tempAddress&0xFFE0 | uint16(*data>>3)
+			this.fineX = *data & 0x07
+		} else {
+			this.tempAddress = this.tempAddress&0x8C1F | uint16(*data&0x07)<<12 | uint16(*data>>3)<<5
+		}
+		this.writeToggle = !this.writeToggle
 	case 0x0006:
-		break
+		if !this.writeToggle {
+			this.tempAddress = this.tempAddress&0x00FF | uint16(*data&0x3F)<<8
+		} else {
+			this.tempAddress = this.tempAddress&0xFF00 | uint16(*data)
+			this.vramAddress = this.tempAddress
+		}
+		this.writeToggle = !this.writeToggle
 	case 0x0007:
-		break
+		this.PPUWrite(this.vramAddress, data)
+		this.incrementVRAMAddress()
 	}
 }
 
 func (this *PPU) CPURead(addr uint16, readOnly bool) uint8 {
-	var data uint8 = 0x00
+	data := this.ioLatch
 
 	switch addr {
 	case 0x0000:
@@ -37,36 +99,180 @@ func (this *PPU) CPURead(addr uint16, readOnly bool) uint8 {
 	case 0x0001:
 		break
 	case 0x0002:
-		break
+		data = this.status&0xE0 | this.ioLatch&0x1F
+		if !readOnly {
+			this.status &^= PPU_STATUS_VBLANK
+			this.writeToggle = false
+			this.ioLatch = data
+		}
 	case 0x0003:
 		break
 	case 0x0004:
-		break
+		data = this.oam[this.oamAddress]
+		if !readOnly {
+			this.ioLatch = data
+		}
 	case 0x0005:
 		break
 	case 0x0006:
 		break
 	case 0x0007:
-		break
+		// Reads go through a buffer, except palette reads which answer
+		// at once while refilling the buffer from the nametable below.
+		if readOnly {
+			return this.readBuffer
+		}
+		data = this.readBuffer
+		this.readBuffer = this.PPURead(this.vramAddress, false)
+		if this.vramAddress&0x3FFF >= 0x3F00 {
+			data = this.ioLatch&0xC0 | this.readBuffer&0x3F
+			this.readBuffer = this.PPURead(this.vramAddress-0x1000, false)
+		}
+		this.ioLatch = data
+		this.incrementVRAMAddress()
 	}
 
 	return data
 }
 
+// PPU memory map: pattern tables at $0000-$1FFF and nametables at
+// $2000-$3EFF go to the cartridge first, which may claim any of them; the
+// console's 2KB of nametable RAM answers the rest, mirrored as the board
+// wires it. Palettes sit at $3F00-$3FFF.
 func (this *PPU) PPUWrite(addr uint16, data *uint8) {
 	addr &= 0x3FFF
+
+	if this.cartridge != nil {
+		this.cartridge.PPUAddress(addr)
+	}
+
+	if this.cartridge != nil && this.cartridge.PPUWrite(addr, data) {
+		return
+	}
+
+	if addr >= 0x2000 && addr <= 0x3EFF {
+		this.vram_nameTable[this.nameTableIndex(addr)][addr&0x03FF] = *data
+	} else if addr >= 0x3F00 {
+		this.vram_paletteTable[paletteIndex(addr)] = *data
+	}
 }
 
 func (this *PPU) PPURead(addr uint16, readOnly bool) uint8 {
 	var data uint8 = 0x00
 	addr &= 0x3FFF
+
+	if this.cartridge != nil && !readOnly {
+		this.cartridge.PPUAddress(addr)
+	}
+
+	if this.cartridge != nil {
+		if cartridgeData, driven := this.cartridge.PPURead(addr, readOnly); driven {
+			return cartridgeData
+		}
+	}
+
+	if addr >= 0x2000 && addr <= 0x3EFF {
+		data = this.vram_nameTable[this.nameTableIndex(addr)][addr&0x03FF]
+	} else if addr >= 0x3F00 {
+		data = this.vram_paletteTable[paletteIndex(addr)]
+	}
+
 	return data
 }
 
+// nameTableIndex picks which of the two physical nametables serves one of
+// the four logical ones at $2000, $2400, $2800 and $2C00.
+func (this *PPU) nameTableIndex(addr uint16) int {
+	mirroring := MIRROR_HORIZONTAL
+	if this.cartridge != nil {
+		mirroring = this.cartridge.Mirroring()
+	}
+
+	switch mirroring {
+	case MIRROR_VERTICAL, MIRROR_FOUR_SCREEN:
+		return int(addr>>10) & 0x01
+	case MIRROR_SINGLE_SCREEN_LOW:
+		return 0
+	case MIRROR_SINGLE_SCREEN_HIGH:
+		return 1
+	case MIRROR_MAPPER_CONTROLLED:
+		return this.cartridge.CIRAMPage(addr)
+	default:
+		return int(addr>>11) & 0x01
+	}
+}
+
+// paletteIndex folds $3F10, $3F14, $3F18 and $3F1C onto the background
+// entries they mirror.
+func paletteIndex(addr uint16) uint16 {
+	addr &= 0x001F
+	if addr&0x0013 == 0x0010 {
+		addr &= 0x000F
+	}
+	return addr
+}
+
 func (this *PPU) ConnectCartridge(cartridge *Cartridge) {
 	this.cartridge = cartridge
 }
 
+func (this *PPU) setRegion(region Region) {
+	this.timing = regionTimings[region]
+}
+
+// clock advances the PPU by one dot. Dot 1 of the vblank scanline starts
+// vblank and the pre-render scanline clears it; on NTSC with rendering on,
+// odd frames skip the last dot of the pre-render scanline.
 func (this *PPU) clock() {
+	preRenderScanline := this.timing.scanlinesPerFrame - 1
+
+	if this.scanline == this.timing.vblankScanline && this.dot == 1 {
+		this.status |= PPU_STATUS_VBLANK
+		if this.control&PPU_CTRL_NMI_ENABLE != 0 {
+			this.nmi = true
+		}
+	}
+
+	if this.scanline == preRenderScanline && this.dot == 1 {
+		this.status &^= PPU_STATUS_VBLANK
+	}
+
+	isRenderingEnabled := this.mask&PPU_MASK_RENDERING_ENABLED != 0
+	if isRenderingEnabled && (this.scanline < PPU_SCREEN_HEIGHT || this.scanline == preRenderScanline) {
+		this.renderDot(this.scanline == preRenderScanline)
+	}
+
+	this.dot++
+
+	if this.scanline == preRenderScanline && this.dot == PPU_DOTS_PER_SCANLINE-1 && this.oddFrame && isRenderingEnabled && this.timing.skipsOddFrameDot {
+		this.dot++
+	}
+
+	if this.dot >= PPU_DOTS_PER_SCANLINE {
+		this.dot = 0
+		this.scanline++
+
+		if this.scanline >= this.timing.scanlinesPerFrame {
+			this.scanline = 0
+			this.oddFrame = !this.oddFrame
+			this.frameComplete = true
+		}
+	}
+}
+
+func (this *PPU) reset() {
+	this.control = 0
+	this.mask = 0
+	this.scanline = 0
+	this.dot = 0
+	this.oddFrame = false
+	this.frameComplete = false
+	this.nmi = false
+	this.writeToggle = false
+	this.readBuffer = 0
+}
 
+// Frame is the last rendered picture, as NES palette color numbers.
+func (this *PPU) Frame() *[PPU_SCREEN_HEIGHT][PPU_SCREEN_WIDTH]uint8 {
+	return &this.frame
 }
@@ -0,0 +1,231 @@
+package components
+
+const (
+	PPU_SCREEN_WIDTH  = 256
+	PPU_SCREEN_HEIGHT = 240
+
+	PPU_CTRL_SPRITE_TABLE     = 0x08
+	PPU_CTRL_BACKGROUND_TABLE = 0x10
+	PPU_CTRL_SPRITES_8X16     = 0x20
+	PPU_MASK_BACKGROUND_LEFT  = 0x02
+	PPU_MASK_SHOW_BACKGROUND  = 0x08
+)
+
+// backgroundPipeline holds the tile being fetched and the 16-bit shift
+// registers feeding pixels out, two tiles ahead of the beam.
+type backgroundPipeline struct {
+	nextTileID        uint8
+	nextTileAttribute uint8
+	nextTileLow       uint8
+	nextTileHigh      uint8
+
+	patternLow    uint16
+	patternHigh   uint16
+	attributeLow  uint16
+	attributeHigh uint16
+}
+
+// renderDot performs the memory fetches and pixel output of one dot on a
+// visible or pre-render scanline, with the timing of the 2C02 so boards
+// watching the PPU bus see the same sequence of addresses:
+//
+//	dots 1-256    background tiles 2-33, 4 fetches per 8 dots
+//	dots 257-320  8 sprite slots: 2 nametable fetches, pattern low, high
+//	dots 321-336  background tiles 0-1 of the next scanline
+//	dots 337-340  2 unused nametable fetches
+//
+// Sprites are not evaluated yet; every slot fetches tile $FF as the PPU
+// does for empty slots.
+func (this *PPU) renderDot(isPreRender bool) {
+	dot := this.dot
+
+	isBackgroundFetch := (dot >= 1 && dot <= 256) || (dot >= 321 && dot <= 336)
+
+	if (dot >= 2 && dot <= 257) || (dot >= 322 && dot <= 337) {
+		this.shiftBackground()
+	}
+
+	if isBackgroundFetch {
+		switch (dot - 1) % 8 {
+		case 0:
+			if dot != 1 && dot != 321 {
+				this.loadBackgroundShifters()
+			}
+			this.background.nextTileID = this.PPURead(0x2000|this.vramAddress&0x0FFF, false)
+		case 2:
+			this.fetchAttribute()
+		case 4:
+			this.background.nextTileLow = this.PPURead(this.backgroundPatternAddress(), false)
+		case 6:
+			this.background.nextTileHigh = this.PPURead(this.backgroundPatternAddress()+8, false)
+		case 7:
+			this.incrementCoarseX()
+		}
+	}
+
+	switch {
+	case dot == 256:
+		this.incrementY()
+	case dot == 257:
+		this.loadBackgroundShifters()
+		this.vramAddress = this.vramAddress&^0x041F | this.tempAddress&0x041F
+	case dot >= 257 && dot <= 320:
+		this.fetchEmptySpriteSlot((dot - 257) % 8)
+	case dot == 337:
+		this.loadBackgroundShifters()
+		this.PPURead(0x2000|this.vramAddress&0x0FFF, false)
+	case dot == 339:
+		this.PPURead(0x2000|this.vramAddress&0x0FFF, false)
+	}
+
+	if isPreRender && dot >= 280 && dot <= 304 {
+		this.vramAddress = this.vramAddress&^0x7BE0 | this.tempAddress&0x7BE0
+	}
+
+	if !isPreRender && dot >= 1 && dot <= PPU_SCREEN_WIDTH {
+		this.outputPixel(dot - 1)
+	}
+}
+
+func (this *PPU) fetchAttribute() {
+	v := this.vramAddress
+	attribute := this.PPURead(0x23C0|v&0x0C00|(v>>4)&0x38|(v>>2)&0x07, false)
+
+	if v&0x0040 != 0 {
+		attribute >>= 4
+	}
+	if v&0x0002 != 0 {
+		attribute >>= 2
+	}
+
+	this.background.nextTileAttribute = attribute & 0x03
+}
+
+func (this *PPU) backgroundPatternAddress() uint16 {
+	var table uint16
+	if this.control&PPU_CTRL_BACKGROUND_TABLE != 0 {
+		table = 0x1000
+	}
+	fineY := (this.vramAddress >> 12) & 0x07
+
+	return table | uint16(this.background.nextTileID)<<4 | fineY
+}
+
+func (this *PPU) fetchEmptySpriteSlot(step int) {
+	var table uint16
+	if this.control&PPU_CTRL_SPRITES_8X16 != 0 {
+		table = 0x1000
+	} else if this.control&PPU_CTRL_SPRITE_TABLE != 0 {
+		table = 0x1000
+	}
+
+	switch step {
+	case 0, 2:
+		this.PPURead(0x2000|this.vramAddress&0x0FFF, false)
+	case 4:
+		this.PPURead(table|0x0FF0, false)
+	case 6:
+		this.PPURead(table|0x0FF8, false)
+	}
+}
+
+func (this *PPU) shiftBackground() {
+	this.background.patternLow <<= 1
+	this.background.patternHigh <<= 1
+	this.background.attributeLow <<= 1
+	this.background.attributeHigh <<= 1
+}
+
+func (this *PPU) loadBackgroundShifters() {
+	b := &this.background
+
+	b.patternLow = b.patternLow&0xFF00 | uint16(b.nextTileLow)
+	b.patternHigh = b.patternHigh&0xFF00 | uint16(b.nextTileHigh)
+
+	b.attributeLow &= 0xFF00
+	if b.nextTileAttribute&0x01 != 0 {
+		b.attributeLow |= 0x00FF
+	}
+
+	b.attributeHigh &= 0xFF00
+	if b.nextTileAttribute&0x02 != 0 {
+		b.attributeHigh |= 0x00FF
+	}
+}
+
+func (this *PPU) outputPixel(x int) {
+	var pixel, palette uint8
+
+	showBackground := this.mask&PPU_MASK_SHOW_BACKGROUND != 0 && (x >= 8 || this.mask&PPU_MASK_BACKGROUND_LEFT != 0)
+
+	if showBackground {
+		bit := uint16(0x8000) >> this.fineX
+		b := &this.background
+
+		if b.patternLow&bit != 0 {
+			pixel |= 0x01
+		}
+		if b.patternHigh&bit != 0 {
+			pixel |= 0x02
+		}
+		if b.attributeLow&bit != 0 {
+			palette |= 0x01
+		}
+		if b.attributeHigh&bit != 0 {
+			palette |= 0x02
+		}
+	}
+
+	// Transparent pixels show the backdrop color.
+	if pixel == 0 {
+		palette = 0
+	}
+
+	this.frame[this.scanline][x] = this.vram_paletteTable[paletteIndex(uint16(palette)<<2|uint16(pixel))] & 0x3F
+}
+
+// incrementCoarseX moves to the next tile, switching to the horizontally
+// adjacent nametable at the end of a row.
+func (this *PPU) incrementCoarseX() {
+	if this.vramAddress&0x001F == 31 {
+		this.vramAddress &^= 0x001F
+		this.vramAddress ^= 0x0400
+	} else {
+		this.vramAddress++
+	}
+}
+
+// incrementY moves to the next pixel row, wrapping coarse Y at row 29 into
+// the vertically adjacent nametable. Rows 30 and 31 wrap without switching.
+func (this *PPU) incrementY() {
+	if this.vramAddress&0x7000 != 0x7000 {
+		this.vramAddress += 0x1000
+		return
+	}
+
+	this.vramAddress &^= 0x7000
+	coarseY := (this.vramAddress & 0x03E0) >> 5
+
+	switch coarseY {
+	case 29:
+		coarseY = 0
+		this.vramAddress ^= 0x0800
+	case 31:
+		coarseY = 0
+	default:
+		coarseY++
+	}
+
+	this.vramAddress = this.vramAddress&^0x03E0 | coarseY<<5
+}
+
+// incrementVRAMAddress steps the address after a $2007 access, by 1 or 32
+// as PPUCTRL bit 2 selects.
+func (this *PPU) incrementVRAMAddress() {
+	if this.control&PPU_CTRL_INCREMENT_32 != 0 {
+		this.vramAddress += 32
+	} else {
+		this.vramAddress++
+	}
+	this.vramAddress &= 0x7FFF
+}
@@ -0,0 +1,40 @@
+package components
+
+var _ CPUBus = (*RecordingBus)(nil)
+
+type BusAccess struct {
+	Addr  uint16
+	Data  uint8
+	Write bool
+}
+
+// RecordingBus sits between CPU6502 and another CPUBus and keeps every read
+// and write that reaches it, in order. Read-only peeks are forwarded but not
+// recorded, since the hardware never performs them.
+type RecordingBus struct {
+	bus      CPUBus
+	Accesses []BusAccess
+}
+
+func NewRecordingBus(bus CPUBus) *RecordingBus {
+	return &RecordingBus{bus: bus}
+}
+
+func (this *RecordingBus) CPUWrite(addr uint16, data uint8) {
+	this.Accesses = append(this.Accesses, BusAccess{Addr: addr, Data: data, Write: true})
+	this.bus.CPUWrite(addr, data)
+}
+
+func (this *RecordingBus) CPURead(addr uint16, readOnly bool) uint8 {
+	data := this.bus.CPURead(addr, readOnly)
+
+	if !readOnly {
+		this.Accesses = append(this.Accesses, BusAccess{Addr: addr, Data: data, Write: false})
+	}
+
+	return data
+}
+
+func (this *RecordingBus) Clear() {
+	this.Accesses = nil
+}
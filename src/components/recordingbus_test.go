@@ -0,0 +1,73 @@
+package components
+
+import (
+	"reflect"
+	"testing"
+)
+
+// recordINC runs INC $0300 on $41 and returns the bus traffic it made.
+func recordINC(cycleAccurate bool) []BusAccess {
+	memory := NewFlatMemory()
+	memory.Load(testProgramAddr, []uint8{0xEE, 0x00, 0x03}) // INC $0300
+	memory.RAM[0x0300] = 0x41
+
+	recorder := NewRecordingBus(memory)
+	cpu := newTestCPU(memory, cycleAccurate)
+	cpu.ConnectBus(recorder)
+
+	runInstruction(cpu)
+
+	return recorder.Accesses
+}
+
+func TestRecordingBusRecordsINC(t *testing.T) {
+	expected := []BusAccess{
+		{Addr: 0x0200, Data: 0xEE},
+		{Addr: 0x0201, Data: 0x00},
+		{Addr: 0x0202, Data: 0x03},
+		{Addr: 0x0300, Data: 0x41},
+		{Addr: 0x0300, Data: 0x42, Write: true},
+	}
+
+	if accesses := recordINC(false); !reflect.DeepEqual(accesses, expected) {
+		t.Errorf("accesses %+v, want %+v", accesses, expected)
+	}
+}
+
+// The cycle-accurate mode also makes the dummy write of the unmodified
+// value, which is what mapper registers see.
+func TestRecordingBusRecordsINCDummyWrite(t *testing.T) {
+	expected := []BusAccess{
+		{Addr: 0x0200, Data: 0xEE},
+		{Addr: 0x0201, Data: 0x00},
+		{Addr: 0x0202, Data: 0x03},
+		{Addr: 0x0300, Data: 0x41},
+		{Addr: 0x0300, Data: 0x41, Write: true},
+		{Addr: 0x0300, Data: 0x42, Write: true},
+	}
+
+	if accesses := recordINC(true); !reflect.DeepEqual(accesses, expected) {
+		t.Errorf("accesses %+v, want %+v", accesses, expected)
+	}
+}
+
+func TestRecordingBusSkipsReadOnlyReads(t *testing.T) {
+	memory := NewFlatMemory()
+	memory.RAM[0x1234] = 0x56
+
+	recorder := NewRecordingBus(memory)
+
+	if data := recorder.CPURead(0x1234, true); data != 0x56 {
+		t.Errorf("read-only read returned $%02X, want $56", data)
+	}
+	if len(recorder.Accesses) != 0 {
+		t.Errorf("read-only read was recorded: %+v", recorder.Accesses)
+	}
+
+	recorder.CPURead(0x1234, false)
+	recorder.Clear()
+
+	if len(recorder.Accesses) != 0 {
+		t.Errorf("Clear left %+v", recorder.Accesses)
+	}
+}
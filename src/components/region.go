@@ -0,0 +1,64 @@
+package components
+
+// Region selects the console timing. All three share the 341-dot scanline;
+// they differ in how the master clock is divided and in the frame height.
+type Region uint8
+
+const (
+	REGION_NTSC Region = iota
+	REGION_PAL
+	REGION_DENDY
+)
+
+type regionTiming struct {
+	// Master clock dividers. NTSC runs 3 dots per CPU cycle, PAL 3.2 and
+	// Dendy 3.
+	ppuClockDivider   uint8
+	cpuClockDivider   uint8
+	scanlinesPerFrame int
+	vblankScanline    int
+	skipsOddFrameDot  bool
+}
+
+// Dendy keeps the 312-line PAL frame but starts vblank 51 lines after the
+// picture, so the NMI lands about as far into the frame as on NTSC.
+var regionTimings = [...]regionTiming{
+	REGION_NTSC:  {ppuClockDivider: 4, cpuClockDivider: 12, scanlinesPerFrame: 262, vblankScanline: 241, skipsOddFrameDot: true},
+	REGION_PAL:   {ppuClockDivider: 5, cpuClockDivider: 16, scanlinesPerFrame: 312, vblankScanline: 241},
+	REGION_DENDY: {ppuClockDivider: 5, cpuClockDivider: 15, scanlinesPerFrame: 312, vblankScanline: 291},
+}
+
+func (this Region) String() string {
+	switch this {
+	case REGION_PAL:
+		return "PAL"
+	case REGION_DENDY:
+		return "Dendy"
+	default:
+		return "NTSC"
+	}
+}
+
+// regionFromHeader reads the TV system of an iNES or NES 2.0 header. NES 2.0
+// uses the low two bits of byte 12 (multi-region carts run as NTSC), iNES only
+// flags PAL in bit 0 of byte 9.
+func regionFromHeader(header []uint8) Region {
+	isNES20 := header[7]&0x0C == 0x08
+
+	if isNES20 {
+		switch header[12] & 0x03 {
+		case 1:
+			return REGION_PAL
+		case 3:
+			return REGION_DENDY
+		default:
+			return REGION_NTSC
+		}
+	}
+
+	if header[9]&0x01 != 0 {
+		return REGION_PAL
+	}
+
+	return REGION_NTSC
+}